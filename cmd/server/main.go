@@ -9,6 +9,7 @@ import (
 	"todo-api/internal/middleware"
 	"todo-api/pkg/db"
 	"todo-api/pkg/logger"
+	"todo-api/pkg/storage"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/joho/godotenv"
@@ -32,11 +33,26 @@ func main() {
 		panic(err)
 	}
 
+	if err := storage.InitStorage(); err != nil {
+		panic(err)
+	}
+
+	// Окончательно удаляем задачи, пролежавшие в корзине дольше срока хранения
+	handlers.StartTrashPurge(context.Background())
+
 	// Защищённые эндпоинты
 	http.HandleFunc("/tasks", middleware.AuthMiddleware(handlers.TasksHandler))
 	http.HandleFunc("/tasks/", middleware.AuthMiddleware(handlers.TaskHandler))
+	http.HandleFunc("/tasks:batch", middleware.AuthMiddleware(handlers.TasksBatchHandler))
+	http.HandleFunc("/tags", middleware.AuthMiddleware(handlers.TagsHandler))
+	http.HandleFunc("/projects", middleware.AuthMiddleware(handlers.ProjectsHandler))
+	http.HandleFunc("/projects/", middleware.AuthMiddleware(handlers.ProjectHandler))
 
 	http.HandleFunc("/users", middleware.AuthMiddleware(handlers.UsersHandler))
+	http.HandleFunc("/calendar/token", middleware.AuthMiddleware(handlers.CalendarTokenHandler))
+
+	// Календарь задач защищён секретом в ссылке, а не Bearer-токеном
+	http.HandleFunc("/calendar/", handlers.CalendarFeedHandler)
 
 	ctx := context.Background()
 	pong, err := redisClient.Ping(ctx).Result()
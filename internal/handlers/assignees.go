@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"time"
+	"todo-api/internal/models"
+	"todo-api/pkg/db"
+	"todo-api/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// assignedTaskIDs — подзапрос с задачами, назначенными пользователю
+func assignedTaskIDs(userID int) *gorm.DB {
+	return db.DB.Model(&models.TaskAssignee{}).Select("task_id").Where("user_id = ?", userID)
+}
+
+// isTaskAssignee сообщает, назначена ли задача пользователю
+func isTaskAssignee(tx *gorm.DB, taskID, userID int) (bool, error) {
+	var count int64
+	err := tx.Model(&models.TaskAssignee{}).Where("task_id = ? AND user_id = ?", taskID, userID).Count(&count).Error
+	return count > 0, err
+}
+
+// assigneeIDs возвращает идентификаторы исполнителей по возрастанию без повторов
+func assigneeIDs(assignees []models.TaskAssignee) []int {
+	ids := make([]int, 0, len(assignees))
+	for _, a := range assignees {
+		ids = append(ids, a.UserID)
+	}
+	slices.Sort(ids)
+	return slices.Compact(ids)
+}
+
+// checkAssigneeChange запрещает менять исполнителей без полного доступа к
+// задаче: исполнитель может работать с задачей, но не передавать её другим.
+// nil означает, что исполнители не передавались.
+func checkAssigneeChange(existing models.Task, assignees []models.TaskAssignee, userID int, role string) error {
+	if assignees == nil || slices.Equal(assigneeIDs(existing.Assignees), assigneeIDs(assignees)) {
+		return nil
+	}
+	if taskAccess(existing, userID, role) < accessManage {
+		return newHTTPError(http.StatusForbidden, "Недостаточно прав для назначения исполнителей")
+	}
+	return nil
+}
+
+// replaceTaskAssignees заменяет исполнителей задачи. Уже назначенные
+// сохраняют время и автора назначения. nil оставляет исполнителей без
+// изменений, пустой список снимает всех.
+func replaceTaskAssignees(tx *gorm.DB, t *models.Task, assignees []models.TaskAssignee, assignedBy int) error {
+	if assignees == nil {
+		return nil
+	}
+	ids := assigneeIDs(assignees)
+	if len(ids) > 0 {
+		var count int64
+		if err := tx.Model(&models.User{}).Where("id IN ?", ids).Count(&count).Error; err != nil {
+			return err
+		}
+		if int(count) != len(ids) {
+			return newHTTPError(http.StatusBadRequest, "Исполнитель не найден")
+		}
+	}
+
+	removed := tx.Where("task_id = ?", t.ID)
+	if len(ids) > 0 {
+		removed = removed.Where("user_id NOT IN ?", ids)
+	}
+	if err := removed.Delete(&models.TaskAssignee{}).Error; err != nil {
+		return err
+	}
+	var current []int
+	if err := tx.Model(&models.TaskAssignee{}).Where("task_id = ?", t.ID).Pluck("user_id", &current).Error; err != nil {
+		return err
+	}
+	var added []models.TaskAssignee
+	for _, id := range ids {
+		if !slices.Contains(current, id) {
+			added = append(added, models.TaskAssignee{TaskID: t.ID, UserID: id, AssignedBy: assignedBy})
+		}
+	}
+	if len(added) > 0 {
+		if err := tx.Create(&added).Error; err != nil {
+			return err
+		}
+	}
+	t.Assignees = []models.TaskAssignee{}
+	return tx.Where("task_id = ?", t.ID).Order("user_id").Find(&t.Assignees).Error
+}
+
+// addedAssignees возвращает исполнителей, которых не было у задачи до изменения
+func addedAssignees(before *models.Task, after models.Task) []int {
+	var previous []int
+	if before != nil {
+		previous = assigneeIDs(before.Assignees)
+	}
+	var added []int
+	for _, id := range assigneeIDs(after.Assignees) {
+		if !slices.Contains(previous, id) {
+			added = append(added, id)
+		}
+	}
+	return added
+}
+
+// notifyAssignees уведомляет новых исполнителей задачи, не задерживая
+// ответ. Тот, кто назначил задачу сам себе, уведомление не получает.
+func notifyAssignees(t models.Task, assignedBy int, userIDs []int) {
+	for _, id := range userIDs {
+		if id == assignedBy {
+			continue
+		}
+		go func(id int) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			ch := make(chan string, 1)
+			notifyUser(ctx, id, t.Title, "назначена вам", ch)
+			logger.Log.Infof("Результат уведомления: %s", <-ch)
+		}(id)
+	}
+}
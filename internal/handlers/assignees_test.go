@@ -0,0 +1,97 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"todo-api/internal/handlers"
+	"todo-api/internal/models"
+	"todo-api/pkg/db"
+)
+
+func TestTaskAssignees(t *testing.T) {
+	schemaName := initTestDB()
+	defer db.DB.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schemaName))
+
+	users := []models.User{
+		{Username: "manager", Password: "hashed", Role: models.RoleUser},
+		{Username: "worker", Password: "hashed", Role: models.RoleUser},
+		{Username: "outsider", Password: "hashed", Role: models.RoleUser},
+	}
+	db.DB.Create(&users)
+	manager, worker, outsider := users[0].ID, users[1].ID, users[2].ID
+
+	task := createTask(t, manager, fmt.Sprintf(`{"title":"Prepare release","assignees":[{"user_id":%d}]}`, worker))
+	if len(task.Assignees) != 1 || task.Assignees[0].UserID != worker || task.Assignees[0].AssignedBy != manager {
+		t.Fatalf("Ожидался исполнитель %d, назначенный %d, получено %+v", worker, manager, task.Assignees)
+	}
+	createTask(t, worker, `{"title":"Own task"}`)
+
+	do := func(method string, userID int, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, fmt.Sprintf("/tasks/%d", task.ID), strings.NewReader(body))
+		req.Header.Set("UserID", fmt.Sprintf("%d", userID))
+		req.Header.Set("Role", models.RoleUser)
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		rr := httptest.NewRecorder()
+		handlers.TaskHandler(rr, req)
+		return rr
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		userID     int
+		body       string
+		wantStatus int
+	}{
+		{"Исполнитель видит задачу", "GET", worker, "", http.StatusOK},
+		{"Исполнитель меняет задачу", "PATCH", worker, `{"status":"in_progress"}`, http.StatusOK},
+		{"Исполнитель не передаёт задачу", "PATCH", worker, fmt.Sprintf(`{"assignees":[{"user_id":%d}]}`, outsider), http.StatusForbidden},
+		{"Исполнитель не удаляет задачу", "DELETE", worker, "", http.StatusForbidden},
+		{"Посторонний не видит задачу", "GET", outsider, "", http.StatusNotFound},
+		{"Неизвестный исполнитель", "PATCH", manager, `{"assignees":[{"user_id":999}]}`, http.StatusBadRequest},
+		{"Создатель добавляет исполнителя", "PATCH", manager, fmt.Sprintf(`{"assignees":[{"user_id":%d},{"user_id":%d}]}`, worker, outsider), http.StatusOK},
+		{"Новый исполнитель видит задачу", "GET", outsider, "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		if rr := do(tt.method, tt.userID, tt.body); rr.Code != tt.wantStatus {
+			t.Errorf("%s: ожидался статус %v, получен %v: %s", tt.name, tt.wantStatus, rr.Code, rr.Body.String())
+		}
+	}
+
+	var assignees []models.TaskAssignee
+	db.DB.Where("task_id = ?", task.ID).Order("user_id").Find(&assignees)
+	if len(assignees) != 2 || !assignees[0].CreatedAt.Before(assignees[1].CreatedAt) {
+		t.Errorf("Ожидались два исполнителя, первый сохраняет время назначения: %+v", assignees)
+	}
+
+	filters := []struct {
+		query     string
+		userID    int
+		wantCount int
+	}{
+		{"?assignee=me", worker, 1},
+		{"?created_by=me", worker, 1},
+		{"", worker, 2},
+		{"?assignee=me", manager, 0},
+		{fmt.Sprintf("?assignee=%d", worker), manager, 1},
+	}
+	for _, f := range filters {
+		req, _ := http.NewRequest("GET", "/tasks"+f.query, nil)
+		req.Header.Set("UserID", fmt.Sprintf("%d", f.userID))
+		req.Header.Set("Role", models.RoleUser)
+		rr := httptest.NewRecorder()
+		handlers.TasksHandler(rr, req)
+
+		var tasks []models.Task
+		if err := json.NewDecoder(rr.Body).Decode(&tasks); err != nil {
+			t.Fatalf("%s: ошибка десериализации: %v", f.query, err)
+		}
+		if len(tasks) != f.wantCount {
+			t.Errorf("Пользователь %d, %s: ожидалось %d задач, получено %d", f.userID, f.query, f.wantCount, len(tasks))
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"todo-api/internal/models"
+	"todo-api/pkg/db"
+	"todo-api/pkg/logger"
+	"todo-api/pkg/storage"
+
+	"gorm.io/gorm"
+)
+
+// Размер вложения по умолчанию, переопределяется ATTACHMENT_MAX_SIZE
+const defaultAttachmentMaxSize = 10 << 20
+
+// Типы файлов, которые можно прикреплять к задачам. Тип определяется
+// по содержимому, а не по заголовку клиента.
+var allowedAttachmentTypes = map[string]bool{
+	"application/pdf": true,
+	"application/zip": true,
+	"image/gif":       true,
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/webp":      true,
+	"text/plain":      true,
+}
+
+func attachmentMaxSize() int64 {
+	if size, err := strconv.ParseInt(os.Getenv("ATTACHMENT_MAX_SIZE"), 10, 64); err == nil && size > 0 {
+		return size
+	}
+	return defaultAttachmentMaxSize
+}
+
+// Обработчик для вложений: /tasks/{id}/attachments[/{attachment_id}]
+func taskAttachmentsHandler(w http.ResponseWriter, r *http.Request, id int, rest []string, userID int, role string) {
+	t, ok := findTask(id, userID, role)
+	if !ok {
+		http.Error(w, "Задача не найдена", http.StatusNotFound)
+		return
+	}
+
+	if len(rest) == 0 {
+		switch r.Method {
+		case "GET":
+			attachments := []models.Attachment{}
+			if err := db.DB.Where("task_id = ?", id).Order("id").Find(&attachments).Error; err != nil {
+				writeError(w, err, "Ошибка получения вложений")
+				return
+			}
+			json.NewEncoder(w).Encode(attachments)
+		case "POST":
+			if taskAccess(t, userID, role) < accessEdit {
+				http.Error(w, "Недостаточно прав", http.StatusForbidden)
+				return
+			}
+			a, err := uploadAttachment(w, r, id, userID)
+			if err != nil {
+				writeError(w, err, "Ошибка загрузки вложения")
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(a)
+		default:
+			http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	attachmentID, err := strconv.Atoi(rest[0])
+	if err != nil {
+		http.Error(w, "Некорректный ID вложения", http.StatusBadRequest)
+		return
+	}
+	var a models.Attachment
+	if err := db.DB.Where("task_id = ?", id).First(&a, attachmentID).Error; err != nil {
+		http.Error(w, "Вложение не найдено", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		content, err := storage.Store.Get(r.Context(), a.StorageKey)
+		if err != nil {
+			writeError(w, err, "Ошибка чтения вложения")
+			return
+		}
+		defer content.Close()
+
+		w.Header().Set("Content-Type", a.ContentType)
+		w.Header().Set("Content-Length", strconv.FormatInt(a.Size, 10))
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename}))
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		if _, err := io.Copy(w, content); err != nil {
+			logger.Log.Warnf("Ошибка отправки вложения %d: %v", a.ID, err)
+		}
+	case "DELETE":
+		if taskAccess(t, userID, role) < accessEdit {
+			http.Error(w, "Недостаточно прав", http.StatusForbidden)
+			return
+		}
+		if err := db.DB.Delete(&a).Error; err != nil {
+			writeError(w, err, "Ошибка удаления вложения")
+			return
+		}
+		removeBlobs([]string{a.StorageKey})
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+	}
+}
+
+// uploadAttachment принимает файл из поля file формы multipart/form-data,
+// проверяет размер и тип, сохраняет содержимое в хранилище и метаданные в БД
+func uploadAttachment(w http.ResponseWriter, r *http.Request, taskID, userID int) (models.Attachment, error) {
+	maxSize := attachmentMaxSize()
+	// Запас на служебные части формы
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize+1<<20)
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return models.Attachment{}, newHTTPError(http.StatusUnsupportedMediaType, "Ожидается multipart/form-data")
+	}
+	var part io.Reader
+	var filename string
+	for {
+		p, err := reader.NextPart()
+		if err == io.EOF {
+			return models.Attachment{}, newHTTPError(http.StatusBadRequest, "Файл не передан в поле file")
+		}
+		if err != nil {
+			return models.Attachment{}, uploadError(err, maxSize)
+		}
+		if p.FormName() == "file" {
+			part, filename = p, filepath.Base(p.FileName())
+			break
+		}
+	}
+	if filename == "." || filename == "/" {
+		filename = "file"
+	}
+
+	// Размер нужен хранилищу заранее, поэтому сначала пишем во временный файл
+	tmp, err := os.CreateTemp("", "attachment-*")
+	if err != nil {
+		return models.Attachment{}, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	size, err := io.Copy(tmp, io.LimitReader(part, maxSize+1))
+	if err != nil {
+		return models.Attachment{}, uploadError(err, maxSize)
+	}
+	if size > maxSize {
+		return models.Attachment{}, newHTTPError(http.StatusRequestEntityTooLarge, "Файл больше %d байт", maxSize)
+	}
+
+	sniff := make([]byte, 512)
+	n, _ := tmp.ReadAt(sniff, 0)
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(sniff[:n]))
+	if !allowedAttachmentTypes[contentType] {
+		return models.Attachment{}, newHTTPError(http.StatusUnsupportedMediaType, "Тип файла %s не поддерживается", contentType)
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return models.Attachment{}, err
+	}
+
+	key, err := attachmentKey(taskID)
+	if err != nil {
+		return models.Attachment{}, err
+	}
+	if err := storage.Store.Put(r.Context(), key, tmp, size, contentType); err != nil {
+		return models.Attachment{}, err
+	}
+
+	a := models.Attachment{
+		TaskID:      taskID,
+		UserID:      userID,
+		Filename:    filename,
+		ContentType: contentType,
+		Size:        size,
+		StorageKey:  key,
+	}
+	if err := db.DB.Create(&a).Error; err != nil {
+		removeBlobs([]string{key})
+		return models.Attachment{}, err
+	}
+	return a, nil
+}
+
+func uploadError(err error, maxSize int64) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return newHTTPError(http.StatusRequestEntityTooLarge, "Файл больше %d байт", maxSize)
+	}
+	return newHTTPError(http.StatusBadRequest, "Некорректный запрос")
+}
+
+// attachmentKey генерирует неугадываемый ключ содержимого в хранилище
+func attachmentKey(taskID int) (string, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("tasks/%d/%s", taskID, hex.EncodeToString(random)), nil
+}
+
+// attachmentKeys возвращает ключи содержимого вложений задач
+func attachmentKeys(tx *gorm.DB, taskIDs []int) ([]string, error) {
+	var keys []string
+	err := tx.Model(&models.Attachment{}).Where("task_id IN ?", taskIDs).Pluck("storage_key", &keys).Error
+	return keys, err
+}
+
+// removeBlobs удаляет содержимое из хранилища. Вызывается после удаления
+// записей из БД, поэтому ошибки только логируются.
+func removeBlobs(keys []string) {
+	for _, key := range keys {
+		if err := storage.Store.Delete(context.Background(), key); err != nil {
+			logger.Log.Errorf("Ошибка удаления файла %s из хранилища: %v", key, err)
+		}
+	}
+}
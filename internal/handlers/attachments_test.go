@@ -0,0 +1,112 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"todo-api/internal/handlers"
+	"todo-api/internal/models"
+	"todo-api/pkg/db"
+	"todo-api/pkg/storage"
+)
+
+// uploadRequest собирает multipart-запрос с файлом в поле file
+func uploadRequest(t *testing.T, url, filename string, content []byte) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatalf("Ошибка создания формы: %v", err)
+	}
+	part.Write(content)
+	writer.Close()
+
+	req, _ := http.NewRequest("POST", url, &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestTaskAttachments(t *testing.T) {
+	schemaName := db.InitTestDB()
+	defer db.DB.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schemaName))
+
+	store, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("Ошибка создания хранилища: %v", err)
+	}
+	storage.Store = store
+	t.Setenv("ATTACHMENT_MAX_SIZE", "1024")
+
+	task := createTask(t, 1, `{"title":"Task with files"}`)
+	url := fmt.Sprintf("/tasks/%d/attachments", task.ID)
+
+	tests := []struct {
+		name       string
+		filename   string
+		content    []byte
+		wantStatus int
+	}{
+		{"Текстовый файл", "notes.txt", []byte("meeting notes"), http.StatusCreated},
+		{"Слишком большой файл", "big.txt", bytes.Repeat([]byte("a"), 2048), http.StatusRequestEntityTooLarge},
+		{"Запрещённый тип", "tool.exe", []byte("MZ\x90\x00\x03\x00\x00\x00\x04\x00\x00\x00\xff\xff"), http.StatusUnsupportedMediaType},
+	}
+	var uploaded models.Attachment
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := uploadRequest(t, url, tt.filename, tt.content)
+			req.Header.Set("UserID", "1")
+			rr := httptest.NewRecorder()
+
+			handlers.TaskHandler(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("Ожидался статус %v, получен %v: %s", tt.wantStatus, rr.Code, rr.Body.String())
+			}
+			if rr.Code == http.StatusCreated {
+				json.NewDecoder(rr.Body).Decode(&uploaded)
+			}
+		})
+	}
+
+	// Скачивание доступно владельцу, но не постороннему
+	for userID, want := range map[string]int{"1": http.StatusOK, "2": http.StatusNotFound} {
+		req, _ := http.NewRequest("GET", fmt.Sprintf("%s/%d", url, uploaded.ID), nil)
+		req.Header.Set("UserID", userID)
+		rr := httptest.NewRecorder()
+
+		handlers.TaskHandler(rr, req)
+
+		if rr.Code != want {
+			t.Errorf("Пользователь %s: ожидался статус %v, получен %v", userID, want, rr.Code)
+		}
+		if rr.Code == http.StatusOK && rr.Body.String() != "meeting notes" {
+			t.Errorf("Ожидалось содержимое %q, получено %q", "meeting notes", rr.Body.String())
+		}
+	}
+
+	// Файл остаётся, пока задача лежит в корзине, и удаляется при очистке
+	var saved models.Attachment
+	db.DB.First(&saved, uploaded.ID)
+	req, _ := http.NewRequest("DELETE", fmt.Sprintf("/tasks/%d", task.ID), nil)
+	req.Header.Set("UserID", "1")
+	rr := httptest.NewRecorder()
+	handlers.TaskHandler(rr, req)
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("Ожидался статус %v, получен %v", http.StatusNoContent, rr.Code)
+	}
+	if _, err := store.Get(context.Background(), saved.StorageKey); err != nil {
+		t.Errorf("Файл задачи в корзине должен сохраниться, получено %v", err)
+	}
+	if _, err := handlers.PurgeTrash(0); err != nil {
+		t.Fatalf("Ошибка очистки корзины: %v", err)
+	}
+	if _, err := store.Get(context.Background(), saved.StorageKey); err != storage.ErrNotFound {
+		t.Errorf("Файл должен быть удалён из хранилища, получено %v", err)
+	}
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"todo-api/internal/models"
+	"todo-api/pkg/db"
+	"todo-api/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// Максимальное число операций в одном пакете
+const maxBatchOperations = 500
+
+// Режимы выполнения пакета: atomic откатывает все операции при первой
+// ошибке, best_effort откатывает только неудачные
+const (
+	batchAtomic     = "atomic"
+	batchBestEffort = "best_effort"
+)
+
+// Операции пакета и статусы их успешного выполнения
+var batchOperationStatus = map[string]int{
+	"create":   http.StatusCreated,
+	"update":   http.StatusOK,
+	"complete": http.StatusOK,
+	"delete":   http.StatusNoContent,
+}
+
+// errBatchAborted прерывает транзакцию пакета в режиме atomic
+var errBatchAborted = errors.New("пакет отменён")
+
+// batchRequest — тело POST /tasks:batch
+type batchRequest struct {
+	Mode       string           `json:"mode"`
+	Operations []batchOperation `json:"operations"`
+}
+
+// batchOperation — одна операция пакета
+type batchOperation struct {
+	Op       string          `json:"op"`                 // create, update, complete или delete
+	ID       int             `json:"id,omitempty"`       // Задача для update, complete и delete
+	Task     json.RawMessage `json:"task,omitempty"`     // Новая задача для create или merge patch для update
+	Children string          `json:"children,omitempty"` // cascade или reparent для delete
+	Version  int             `json:"version,omitempty"`  // Ожидаемая версия задачи, как If-Match
+}
+
+// batchResult — результат операции пакета с HTTP-статусом, который вернул
+// бы одиночный запрос
+type batchResult struct {
+	Index  int          `json:"index"`
+	Status int          `json:"status"`
+	Task   *models.Task `json:"task,omitempty"`
+	Error  string       `json:"error,omitempty"`
+}
+
+// Обработчик пакетных операций: POST /tasks:batch. Все операции выполняются
+// в одной транзакции, каждая — в своей точке сохранения.
+func TasksBatchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, _ := strconv.Atoi(r.Header.Get("UserID"))
+	role := r.Header.Get("Role")
+
+	var req batchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Некорректный запрос", http.StatusBadRequest)
+		return
+	}
+	switch req.Mode {
+	case "":
+		req.Mode = batchAtomic
+	case batchAtomic, batchBestEffort:
+	default:
+		http.Error(w, "Неверный параметр mode: ожидается atomic или best_effort", http.StatusBadRequest)
+		return
+	}
+	if len(req.Operations) == 0 || len(req.Operations) > maxBatchOperations {
+		http.Error(w, fmt.Sprintf("Пакет должен содержать от 1 до %d операций", maxBatchOperations), http.StatusBadRequest)
+		return
+	}
+
+	results := make([]batchResult, len(req.Operations))
+	assigned := make([][]int, len(req.Operations)) // Новые исполнители, которых нужно уведомить
+	failed := -1
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		for i, op := range req.Operations {
+			var task *models.Task
+			err := tx.Transaction(func(tx *gorm.DB) error {
+				var err error
+				task, assigned[i], err = runBatchOperation(tx, op, userID, role)
+				return err
+			})
+			results[i] = batchResult{Index: i, Status: batchOperationStatus[op.Op], Task: task}
+			if err == nil {
+				continue
+			}
+			var he *httpError
+			if errors.As(err, &he) {
+				results[i] = batchResult{Index: i, Status: he.Status, Error: he.Message}
+			} else {
+				logger.Log.Errorf("Ошибка операции %d пакета: %v", i, err)
+				results[i] = batchResult{Index: i, Status: http.StatusInternalServerError, Error: "Ошибка выполнения операции"}
+			}
+			if req.Mode == batchAtomic {
+				failed = i
+				return errBatchAborted
+			}
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errBatchAborted) {
+		writeError(w, err, "Ошибка выполнения пакета")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if failed >= 0 {
+		// Остальные операции откатаны или не выполнялись
+		for i := range results {
+			if i != failed {
+				results[i] = batchResult{
+					Index:  i,
+					Status: http.StatusFailedDependency,
+					Error:  fmt.Sprintf("Операция отменена из-за ошибки в операции %d", failed),
+				}
+			}
+		}
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	} else {
+		InvalidateTaskLists()
+		for i, ids := range assigned {
+			if results[i].Task != nil {
+				notifyAssignees(*results[i].Task, userID, ids)
+			}
+		}
+	}
+	json.NewEncoder(w).Encode(map[string][]batchResult{"results": results})
+}
+
+// runBatchOperation выполняет операцию с теми же проверками прав и
+// валидацией, что и одиночные запросы к /tasks и /tasks/{id}. Кроме
+// задачи возвращает новых исполнителей для уведомления после фиксации.
+func runBatchOperation(tx *gorm.DB, op batchOperation, userID int, role string) (*models.Task, []int, error) {
+	if _, ok := batchOperationStatus[op.Op]; !ok {
+		return nil, nil, newHTTPError(http.StatusBadRequest, "Неизвестная операция %q", op.Op)
+	}
+	if op.Op == "create" {
+		var t models.Task
+		if err := json.Unmarshal(op.Task, &t); err != nil {
+			return nil, nil, newHTTPError(http.StatusBadRequest, "Некорректная задача")
+		}
+		if err := insertTask(tx, &t, userID, userID, role); err != nil {
+			return nil, nil, err
+		}
+		return &t, addedAssignees(nil, t), nil
+	}
+
+	existing, ok := findTaskIn(tx, op.ID, userID, role)
+	if !ok {
+		return nil, nil, newHTTPError(http.StatusNotFound, "Задача %d не найдена", op.ID)
+	}
+	if op.Version != 0 && op.Version != existing.Version {
+		return nil, nil, errVersionConflict()
+	}
+	switch op.Op {
+	case "delete":
+		if taskAccess(existing, userID, role) < accessManage {
+			return nil, nil, newHTTPError(http.StatusForbidden, "Недостаточно прав")
+		}
+		return nil, nil, deleteTaskTree(tx, userID, existing, op.Children)
+	default:
+		if taskAccess(existing, userID, role) < accessEdit {
+			return nil, nil, newHTTPError(http.StatusForbidden, "Недостаточно прав")
+		}
+		patch := []byte(op.Task)
+		if op.Op == "complete" {
+			patch = []byte(`{"done":true}`)
+		}
+		t, err := patchTask(tx, existing, patch, userID, role)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &t, addedAssignees(&existing, t), nil
+	}
+}
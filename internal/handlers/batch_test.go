@@ -0,0 +1,88 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"todo-api/internal/handlers"
+	"todo-api/internal/models"
+	"todo-api/pkg/db"
+)
+
+func TestTasksBatchHandler(t *testing.T) {
+	schemaName := db.InitTestDB()
+	defer db.DB.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schemaName))
+
+	own := createTask(t, 1, `{"title":"Own task"}`)
+	foreign := createTask(t, 2, `{"title":"Foreign task"}`)
+
+	batch := func(body string) (int, []int) {
+		req, _ := http.NewRequest("POST", "/tasks:batch", strings.NewReader(body))
+		req.Header.Set("UserID", "1")
+		req.Header.Set("Role", models.RoleUser)
+		rr := httptest.NewRecorder()
+
+		handlers.TasksBatchHandler(rr, req)
+
+		var resp struct {
+			Results []struct {
+				Status int `json:"status"`
+			} `json:"results"`
+		}
+		json.NewDecoder(rr.Body).Decode(&resp)
+		statuses := make([]int, len(resp.Results))
+		for i, r := range resp.Results {
+			statuses[i] = r.Status
+		}
+		return rr.Code, statuses
+	}
+	countTasks := func() int64 {
+		var n int64
+		db.DB.Model(&models.Task{}).Where("user_id = ?", 1).Count(&n)
+		return n
+	}
+
+	ops := fmt.Sprintf(`[
+		{"op":"create","task":{"title":"Created in batch"}},
+		{"op":"update","id":%d,"task":{"title":"Renamed task"}},
+		{"op":"complete","id":%d},
+		{"op":"delete","id":%d}
+	]`, own.ID, own.ID, foreign.ID)
+
+	// Чужая задача не видна, поэтому весь пакет откатывается
+	code, statuses := batch(`{"mode":"atomic","operations":` + ops + `}`)
+	if code != http.StatusUnprocessableEntity {
+		t.Errorf("atomic: ожидался статус %v, получен %v", http.StatusUnprocessableEntity, code)
+	}
+	want := []int{http.StatusFailedDependency, http.StatusFailedDependency, http.StatusFailedDependency, http.StatusNotFound}
+	if fmt.Sprint(statuses) != fmt.Sprint(want) {
+		t.Errorf("atomic: ожидались статусы %v, получены %v", want, statuses)
+	}
+	if n := countTasks(); n != 1 {
+		t.Errorf("atomic: после отката ожидалась 1 задача, найдено %d", n)
+	}
+
+	code, statuses = batch(`{"mode":"best_effort","operations":` + ops + `}`)
+	if code != http.StatusOK {
+		t.Errorf("best_effort: ожидался статус %v, получен %v", http.StatusOK, code)
+	}
+	want = []int{http.StatusCreated, http.StatusOK, http.StatusOK, http.StatusNotFound}
+	if fmt.Sprint(statuses) != fmt.Sprint(want) {
+		t.Errorf("best_effort: ожидались статусы %v, получены %v", want, statuses)
+	}
+	var updated models.Task
+	db.DB.First(&updated, own.ID)
+	if updated.Title != "Renamed task" || !updated.Done {
+		t.Errorf("Задача не обновлена: %+v", updated)
+	}
+	if n := countTasks(); n != 2 {
+		t.Errorf("best_effort: ожидалось 2 задачи, найдено %d", n)
+	}
+
+	if code, _ := batch(`{"mode":"sometimes","operations":` + ops + `}`); code != http.StatusBadRequest {
+		t.Errorf("Неизвестный режим: ожидался статус %v, получен %v", http.StatusBadRequest, code)
+	}
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"todo-api/internal/models"
+	"todo-api/pkg/db"
+	"todo-api/pkg/ical"
+	"todo-api/pkg/logger"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Идентификатор приложения в выгружаемых календарях
+const calendarProdID = "-//todo-api//Tasks//RU"
+
+// Обработчик ссылки на календарь: /calendar/token. POST выпускает новый
+// токен и тем самым отзывает прежний, DELETE отзывает токен.
+func CalendarTokenHandler(w http.ResponseWriter, r *http.Request) {
+	userID, _ := strconv.Atoi(r.Header.Get("UserID"))
+
+	switch r.Method {
+	case "POST":
+		token, err := newCalendarToken()
+		if err != nil {
+			writeError(w, err, "Ошибка создания токена")
+			return
+		}
+		ct := models.CalendarToken{UserID: userID, TokenHash: hashCalendarToken(token)}
+		err = db.DB.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"token_hash", "created_at"}),
+		}).Create(&ct).Error
+		if err != nil {
+			writeError(w, err, "Ошибка создания токена")
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"token":      token,
+			"url":        "/calendar/" + token + ".ics",
+			"created_at": ct.CreatedAt,
+		})
+	case "DELETE":
+		result := db.DB.Where("user_id = ?", userID).Delete(&models.CalendarToken{})
+		if result.Error != nil {
+			writeError(w, result.Error, "Ошибка отзыва токена")
+			return
+		}
+		if result.RowsAffected == 0 {
+			http.Error(w, "Токен не найден", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+	}
+}
+
+// Обработчик календаря задач: GET /calendar/{token}.ics. Подключается без
+// AuthMiddleware, потому что календарные приложения не умеют передавать
+// Bearer-токен: пользователя определяет секрет в ссылке.
+func CalendarFeedHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+	token := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/calendar/"), ".ics")
+	var ct models.CalendarToken
+	if token == "" || db.DB.Where("token_hash = ?", hashCalendarToken(token)).First(&ct).Error != nil {
+		http.Error(w, "Календарь не найден", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	enc := ical.NewEncoder(w)
+	err := enc.Begin(calendarProdID, "Задачи")
+	if err == nil {
+		// В календарь попадают все задачи, видимые пользователю, даже если он администратор
+		var batch []models.Task
+		err = taskScope(db.DB.Model(&models.Task{}), ct.UserID, models.RoleUser, true).
+			Preload("Tags").
+			FindInBatches(&batch, exportBatchSize, func(tx *gorm.DB, _ int) error {
+				for _, t := range batch {
+					if err := enc.Todo(calendarTodo(t)); err != nil {
+						return err
+					}
+				}
+				return nil
+			}).Error
+	}
+	if err == nil {
+		err = enc.End()
+	}
+	if err != nil {
+		// Заголовки уже отправлены, поэтому календарь просто обрывается
+		logger.Log.Errorf("Ошибка выгрузки календаря пользователя %d: %v", ct.UserID, err)
+	}
+}
+
+// calendarTodo переводит задачу в компонент VTODO. Правило повторения
+// получает только открытое повторение: выполненные остаются в календаре
+// отдельными задачами, иначе каждое из них породило бы свою серию. Серия
+// отсчитывается от срока задачи, а без него — от времени создания.
+func calendarTodo(t models.Task) ical.Todo {
+	todo := ical.Todo{
+		UID:          fmt.Sprintf("task-%d@todo-api", t.ID),
+		Summary:      t.Title,
+		Completed:    t.Done,
+		Created:      t.CreatedAt,
+		LastModified: t.UpdatedAt,
+	}
+	if t.DueDate != nil {
+		due := t.DueDate.Time
+		todo.Due = &due
+		todo.DueDateOnly = t.DueDate.DateOnly()
+	}
+	if !t.Done && t.RRule != "" {
+		todo.RRule = t.RRule
+		todo.Start = &t.CreatedAt
+		if todo.Due != nil {
+			todo.Start = todo.Due
+		}
+	}
+	for _, tag := range t.Tags {
+		todo.Categories = append(todo.Categories, tag.Name)
+	}
+	return todo
+}
+
+// newCalendarToken возвращает случайный токен из 256 бит
+func newCalendarToken() (string, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(random), nil
+}
+
+func hashCalendarToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,100 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"todo-api/internal/handlers"
+	"todo-api/internal/models"
+	"todo-api/pkg/db"
+)
+
+func TestCalendarFeed(t *testing.T) {
+	schemaName := db.InitTestDB()
+	defer db.DB.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schemaName))
+
+	task := createTask(t, 1, `{"title":"Pay rent","due_date":"2030-01-05","tags":["home"]}`)
+	createTask(t, 2, `{"title":"Foreign task"}`)
+
+	issue := func() string {
+		req, _ := http.NewRequest("POST", "/calendar/token", nil)
+		req.Header.Set("UserID", "1")
+		req.Header.Set("Role", models.RoleUser)
+		rr := httptest.NewRecorder()
+		handlers.CalendarTokenHandler(rr, req)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("Ожидался статус %v, получен %v", http.StatusCreated, rr.Code)
+		}
+		var resp struct {
+			URL string `json:"url"`
+		}
+		json.NewDecoder(rr.Body).Decode(&resp)
+		return resp.URL
+	}
+	feed := func(url string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", url, nil)
+		rr := httptest.NewRecorder()
+		handlers.CalendarFeedHandler(rr, req)
+		return rr
+	}
+
+	url := issue()
+	rr := feed(url)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус %v, получен %v", http.StatusOK, rr.Code)
+	}
+	body := rr.Body.String()
+	for _, want := range []string{
+		fmt.Sprintf("UID:task-%d@todo-api\r\n", task.ID),
+		"SUMMARY:Pay rent\r\n",
+		"DUE;VALUE=DATE:20300105\r\n",
+		"STATUS:NEEDS-ACTION\r\n",
+		"CATEGORIES:home\r\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("В календаре нет %q:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "Foreign task") {
+		t.Errorf("В календарь попала чужая задача:\n%s", body)
+	}
+
+	// Выполненное повторение остаётся в календаре без правила, и серия
+	// задаётся только следующим открытым повторением
+	recurring := createTask(t, 1, `{"title":"Water plants","due_date":"2030-01-07","rrule":"FREQ=WEEKLY"}`)
+	req, _ := http.NewRequest("PATCH", fmt.Sprintf("/tasks/%d", recurring.ID), strings.NewReader(`{"done":true}`))
+	req.Header.Set("UserID", "1")
+	req.Header.Set("Role", models.RoleUser)
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	patched := httptest.NewRecorder()
+	handlers.TaskHandler(patched, req)
+	if patched.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус %v, получен %v", http.StatusOK, patched.Code)
+	}
+	body = feed(url).Body.String()
+	if n := strings.Count(body, "SUMMARY:Water plants\r\n"); n != 2 {
+		t.Errorf("Ожидалось 2 повторения в календаре, найдено %d:\n%s", n, body)
+	}
+	if n := strings.Count(body, "RRULE:"); n != 1 {
+		t.Errorf("Ожидалось одно правило повторения, найдено %d:\n%s", n, body)
+	}
+	if !strings.Contains(body, "DTSTART;VALUE=DATE:20300114\r\n") {
+		t.Errorf("Серия должна начинаться со срока открытого повторения:\n%s", body)
+	}
+
+	// Новый токен отзывает прежний
+	rotated := issue()
+	if rr := feed(url); rr.Code != http.StatusNotFound {
+		t.Errorf("Старая ссылка: ожидался статус %v, получен %v", http.StatusNotFound, rr.Code)
+	}
+
+	req, _ = http.NewRequest("DELETE", "/calendar/token", nil)
+	req.Header.Set("UserID", "1")
+	handlers.CalendarTokenHandler(httptest.NewRecorder(), req)
+	if rr := feed(rotated); rr.Code != http.StatusNotFound {
+		t.Errorf("Отозванная ссылка: ожидался статус %v, получен %v", http.StatusNotFound, rr.Code)
+	}
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"todo-api/internal/models"
+	"todo-api/pkg/db"
+)
+
+// Обработчик для комментариев: /tasks/{id}/comments[/{comment_id}]
+func taskCommentsHandler(w http.ResponseWriter, r *http.Request, id int, rest []string, userID int, role string) {
+	// Комментировать может любой, кому видна задача
+	if _, ok := findTask(id, userID, role); !ok {
+		http.Error(w, "Задача не найдена", http.StatusNotFound)
+		return
+	}
+
+	if len(rest) == 0 {
+		switch r.Method {
+		case "GET":
+			page, limit := parsePagination(r.URL.Query())
+			comments := []models.Comment{}
+			err := db.DB.Where("task_id = ?", id).
+				Order("created_at, id").
+				Offset((page - 1) * limit).Limit(limit).
+				Find(&comments).Error
+			if err != nil {
+				writeError(w, err, "Ошибка получения комментариев")
+				return
+			}
+			json.NewEncoder(w).Encode(comments)
+		case "POST":
+			var c models.Comment
+			if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+				http.Error(w, "Некорректный запрос", http.StatusBadRequest)
+				return
+			}
+			c.ID = 0
+			c.TaskID = id
+			c.UserID = userID
+			if err := validate.Struct(c); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := db.DB.Create(&c).Error; err != nil {
+				writeError(w, err, "Ошибка создания комментария")
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(c)
+		default:
+			http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	commentID, err := strconv.Atoi(rest[0])
+	if err != nil {
+		http.Error(w, "Некорректный ID комментария", http.StatusBadRequest)
+		return
+	}
+	var c models.Comment
+	if err := db.DB.Where("task_id = ?", id).First(&c, commentID).Error; err != nil {
+		http.Error(w, "Комментарий не найден", http.StatusNotFound)
+		return
+	}
+	// Менять и удалять комментарий могут только автор и администратор
+	if c.UserID != userID && role != models.RoleAdmin {
+		http.Error(w, "Недостаточно прав", http.StatusForbidden)
+		return
+	}
+
+	switch r.Method {
+	case "PUT":
+		var input models.Comment
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Некорректный запрос", http.StatusBadRequest)
+			return
+		}
+		c.Body = input.Body
+		if err := validate.Struct(c); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := db.DB.Model(&c).Update("body", c.Body).Error; err != nil {
+			writeError(w, err, "Ошибка обновления комментария")
+			return
+		}
+		json.NewEncoder(w).Encode(c)
+	case "DELETE":
+		if err := db.DB.Delete(&c).Error; err != nil {
+			writeError(w, err, "Ошибка удаления комментария")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,85 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"todo-api/internal/handlers"
+	"todo-api/internal/models"
+	"todo-api/pkg/db"
+)
+
+func TestTaskComments(t *testing.T) {
+	schemaName := db.InitTestDB()
+	defer db.DB.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schemaName))
+
+	users := []models.User{
+		{Username: "author", Password: "hashed", Role: models.RoleUser},
+		{Username: "reader", Password: "hashed", Role: models.RoleUser},
+	}
+	if err := db.DB.Create(&users).Error; err != nil {
+		t.Fatalf("Ошибка создания тестовых пользователей: %v", err)
+	}
+	author, reader := users[0], users[1]
+
+	task := createTask(t, author.ID, `{"title":"Discussed task"}`)
+	db.DB.Create(&models.TaskShare{TaskID: task.ID, UserID: reader.ID, Permission: models.SharePermissionRead, GrantedBy: author.ID})
+	url := fmt.Sprintf("/tasks/%d/comments", task.ID)
+
+	var comment models.Comment
+	for i := 1; i <= 3; i++ {
+		req, _ := http.NewRequest("POST", url, strings.NewReader(fmt.Sprintf(`{"body":"Comment %d"}`, i)))
+		req.Header.Set("UserID", fmt.Sprintf("%d", author.ID))
+		rr := httptest.NewRecorder()
+		handlers.TaskHandler(rr, req)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("Ожидался статус %v, получен %v: %s", http.StatusCreated, rr.Code, rr.Body.String())
+		}
+		json.NewDecoder(rr.Body).Decode(&comment)
+	}
+
+	// Постраничный список, как у задач
+	req, _ := http.NewRequest("GET", url+"?page=2&limit=2", nil)
+	req.Header.Set("UserID", fmt.Sprintf("%d", reader.ID))
+	rr := httptest.NewRecorder()
+	handlers.TaskHandler(rr, req)
+	var comments []models.Comment
+	if err := json.NewDecoder(rr.Body).Decode(&comments); err != nil {
+		t.Fatalf("Ошибка десериализации ответа: %v", err)
+	}
+	if len(comments) != 1 || comments[0].Body != "Comment 3" {
+		t.Errorf("Ожидался один комментарий Comment 3, получено %+v", comments)
+	}
+
+	commentURL := fmt.Sprintf("%s/%d", url, comment.ID)
+	tests := []struct {
+		name       string
+		method     string
+		userID     int
+		role       string
+		body       string
+		wantStatus int
+	}{
+		{"Чужой комментарий не меняется", "PUT", reader.ID, models.RoleUser, `{"body":"Hijacked"}`, http.StatusForbidden},
+		{"Чужой комментарий не удаляется", "DELETE", reader.ID, models.RoleUser, "", http.StatusForbidden},
+		{"Автор меняет", "PUT", author.ID, models.RoleUser, `{"body":"Edited"}`, http.StatusOK},
+		{"Администратор удаляет", "DELETE", 99, models.RoleAdmin, "", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, commentURL, strings.NewReader(tt.body))
+			req.Header.Set("UserID", fmt.Sprintf("%d", tt.userID))
+			req.Header.Set("Role", tt.role)
+			rr := httptest.NewRecorder()
+
+			handlers.TaskHandler(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("Ожидался статус %v, получен %v", tt.wantStatus, rr.Code)
+			}
+		})
+	}
+}
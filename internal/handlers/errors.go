@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"todo-api/pkg/logger"
+)
+
+// httpError — ошибка, текст которой можно показать клиенту
+type httpError struct {
+	Status  int
+	Message string
+}
+
+func (e *httpError) Error() string {
+	return e.Message
+}
+
+func newHTTPError(status int, format string, args ...interface{}) *httpError {
+	return &httpError{Status: status, Message: fmt.Sprintf(format, args...)}
+}
+
+// writeError отвечает клиенту: httpError — с его статусом и текстом,
+// прочие ошибки логируются и превращаются в 500 с общим сообщением
+func writeError(w http.ResponseWriter, err error, message string) {
+	var he *httpError
+	if errors.As(err, &he) {
+		http.Error(w, he.Message, he.Status)
+		return
+	}
+	logger.Log.Errorf("%s: %v", message, err)
+	http.Error(w, message, http.StatusInternalServerError)
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"todo-api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// taskETag — строгий ETag задачи, производный от её версии
+func taskETag(t models.Task) string {
+	return fmt.Sprintf(`"%d"`, t.Version)
+}
+
+// requireIfMatch включает обязательный If-Match для изменения задач.
+// По умолчанию заголовок учитывается, только если клиент его передал.
+func requireIfMatch() bool {
+	require, _ := strconv.ParseBool(os.Getenv("TASK_REQUIRE_IF_MATCH"))
+	return require
+}
+
+// checkIfMatch сверяет заголовок If-Match с текущей версией задачи
+func checkIfMatch(r *http.Request, t models.Task) error {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" {
+		if requireIfMatch() {
+			return newHTTPError(http.StatusPreconditionRequired, "Требуется заголовок If-Match")
+		}
+		return nil
+	}
+	if header == "*" {
+		return nil
+	}
+	etag := taskETag(t)
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimSpace(candidate) == etag {
+			return nil
+		}
+	}
+	return errVersionConflict()
+}
+
+// payloadETag — строгий ETag, вычисленный по содержимому ответа
+func payloadETag(parts ...[]byte) string {
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write(part)
+		h.Write([]byte{0})
+	}
+	return fmt.Sprintf(`"%x"`, h.Sum(nil)[:16])
+}
+
+// notModified выставляет ETag и, если он совпал с If-None-Match, отвечает
+// 304 Not Modified. If-None-Match сравнивается без учёта слабости (RFC 9110).
+func notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	w.Header().Set("ETag", etag)
+	header := strings.TrimSpace(r.Header.Get("If-None-Match"))
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+func errVersionConflict() error {
+	return newHTTPError(http.StatusPreconditionFailed, "Задача изменена другим пользователем: обновите её и повторите")
+}
+
+// saveTaskVersion сохраняет задачу, только если её версия в базе всё ещё
+// равна expected, и увеличивает версию. Без columns сохраняются все поля.
+// Проверка и запись выполняются одним UPDATE, поэтому два одновременных
+// изменения не перезапишут друг друга.
+func saveTaskVersion(tx *gorm.DB, t *models.Task, expected int, columns ...string) error {
+	t.Version = expected + 1
+	query := tx.Model(t).Where("version = ?", expected)
+	if len(columns) == 0 {
+		query = query.Select("*").Omit("Tags", "Assignees", "CreatedAt")
+	} else {
+		query = query.Select(append(columns, "updated_at", "version"))
+	}
+	result := query.Updates(t)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errVersionConflict()
+	}
+	return nil
+}
+
+// bumpVersion — выражение для служебных изменений задачи, которые тоже
+// должны менять её ETag
+var bumpVersion = gorm.Expr("version + 1")
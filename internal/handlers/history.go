@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"todo-api/internal/models"
+	"todo-api/pkg/db"
+
+	"gorm.io/gorm"
+)
+
+// Поля, которые меняются сами или не хранятся в задаче, в историю не пишутся
+var historyIgnoredFields = map[string]bool{
+	"id":           true,
+	"created_at":   true,
+	"updated_at":   true,
+	"version":      true,
+	"position":     true,
+	"next_task_id": true,
+	"subtasks":     true,
+	"rank":         true,
+	"highlight":    true,
+}
+
+// Обработчик истории задачи: /tasks/{id}/history
+func taskHistoryHandler(w http.ResponseWriter, r *http.Request, id, userID int, role string) {
+	if r.Method != "GET" {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+	// История задачи в корзине тоже доступна: по ней видно, кто её удалил
+	var t models.Task
+	if err := taskScope(db.DB.Unscoped().Model(&models.Task{}), userID, role, true).First(&t, id).Error; err != nil {
+		visible, err := purgedHistoryVisible(id, userID, role)
+		if err != nil {
+			writeError(w, err, "Ошибка получения истории")
+			return
+		}
+		if !visible {
+			http.Error(w, "Задача не найдена", http.StatusNotFound)
+			return
+		}
+	}
+	page, limit := parsePagination(r.URL.Query())
+	history := []models.TaskHistory{}
+	err := db.DB.Where("task_id = ?", id).
+		Order("created_at, id").
+		Offset((page - 1) * limit).Limit(limit).
+		Find(&history).Error
+	if err != nil {
+		writeError(w, err, "Ошибка получения истории")
+		return
+	}
+	json.NewEncoder(w).Encode(history)
+}
+
+// purgedHistoryVisible сообщает, доступна ли пользователю история
+// окончательно удалённой задачи. Самой задачи уже нет, поэтому права
+// определяются по журналу: историю видят администратор, владелец из записи
+// о создании и те, кто менял задачу.
+func purgedHistoryVisible(id, userID int, role string) (bool, error) {
+	var tasks int64
+	if err := db.DB.Unscoped().Model(&models.Task{}).Where("id = ?", id).Count(&tasks).Error; err != nil || tasks > 0 {
+		return false, err
+	}
+	query := db.DB.Model(&models.TaskHistory{}).Where("task_id = ?", id)
+	if role != models.RoleAdmin {
+		query = query.Where("user_id = ? OR (action = ? AND changes -> 'user_id' ->> 'new' = ?)",
+			userID, models.HistoryCreate, strconv.Itoa(userID))
+	}
+	var count int64
+	err := query.Limit(1).Count(&count).Error
+	return count > 0, err
+}
+
+// recordTaskChange записывает в историю создание (before == nil) или
+// изменение задачи. Обновление без изменённых полей не записывается.
+func recordTaskChange(tx *gorm.DB, userID int, before *models.Task, after models.Task) error {
+	action := models.HistoryUpdate
+	if before == nil {
+		action = models.HistoryCreate
+	}
+	changes, err := taskChanges(before, after)
+	if err != nil {
+		return err
+	}
+	if action == models.HistoryUpdate && len(changes) == 0 {
+		return nil
+	}
+	return tx.Create(&models.TaskHistory{TaskID: after.ID, UserID: userID, Action: action, Changes: changes}).Error
+}
+
+// recordTasksAction записывает одинаковую запись истории для нескольких задач
+func recordTasksAction(tx *gorm.DB, ids []int, userID int, action string, changes models.FieldChanges) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	rows := make([]models.TaskHistory, 0, len(ids))
+	for _, id := range ids {
+		rows = append(rows, models.TaskHistory{TaskID: id, UserID: userID, Action: action, Changes: changes})
+	}
+	return tx.Create(&rows).Error
+}
+
+// fieldChange описывает изменение одного поля
+func fieldChange(field string, old, new interface{}) (models.FieldChanges, error) {
+	oldJSON, err := json.Marshal(old)
+	if err != nil {
+		return nil, err
+	}
+	newJSON, err := json.Marshal(new)
+	if err != nil {
+		return nil, err
+	}
+	return models.FieldChanges{field: {Old: oldJSON, New: newJSON}}, nil
+}
+
+// taskChanges сравнивает JSON-представления задачи до и после изменения
+func taskChanges(before *models.Task, after models.Task) (models.FieldChanges, error) {
+	oldFields := map[string]json.RawMessage{}
+	if before != nil {
+		var err error
+		if oldFields, err = taskFields(*before); err != nil {
+			return nil, err
+		}
+	}
+	newFields, err := taskFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	null := json.RawMessage("null")
+	changes := models.FieldChanges{}
+	for field, value := range newFields {
+		old, ok := oldFields[field]
+		if !ok {
+			old = null
+		}
+		if !bytes.Equal(old, value) {
+			changes[field] = models.FieldChange{Old: old, New: value}
+		}
+	}
+	for field, old := range oldFields {
+		if _, ok := newFields[field]; !ok && !bytes.Equal(old, null) {
+			changes[field] = models.FieldChange{Old: old, New: null}
+		}
+	}
+	return changes, nil
+}
+
+// taskFields возвращает сравнимые поля задачи. Метки сортируются, а срок
+// приводится к UTC, чтобы порядок и часовой пояс не считались изменением.
+func taskFields(t models.Task) (map[string]json.RawMessage, error) {
+	t.Tags = append([]models.Tag(nil), t.Tags...)
+	sort.Slice(t.Tags, func(i, j int) bool { return t.Tags[i].Name < t.Tags[j].Name })
+	if t.DueDate != nil {
+		t.DueDate = &models.DueDate{Time: t.DueDate.UTC()}
+	}
+	data, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for field := range historyIgnoredFields {
+		delete(fields, field)
+	}
+	// Исполнители сравниваются по идентификаторам, без времени назначения
+	if fields["assignees"], err = json.Marshal(assigneeIDs(t.Assignees)); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
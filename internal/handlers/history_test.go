@@ -0,0 +1,136 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"todo-api/internal/handlers"
+	"todo-api/internal/models"
+	"todo-api/pkg/db"
+)
+
+func TestTaskHistory(t *testing.T) {
+	schemaName := db.InitTestDB()
+	defer db.DB.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schemaName))
+
+	task := createTask(t, 1, `{"title":"Audited task","tags":["work"]}`)
+	url := fmt.Sprintf("/tasks/%d", task.ID)
+
+	do := func(method, url, userID, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("UserID", userID)
+		req.Header.Set("Role", models.RoleUser)
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		rr := httptest.NewRecorder()
+		handlers.TaskHandler(rr, req)
+		return rr
+	}
+
+	if rr := do("PATCH", url, "1", `{"done":true}`); rr.Code != http.StatusOK {
+		t.Fatalf("PATCH: ожидался статус %v, получен %v", http.StatusOK, rr.Code)
+	}
+	// Обновление без изменений в историю не попадает
+	do("PATCH", url, "1", `{"done":true}`)
+	do("DELETE", url, "1", "")
+	if rr := do("GET", url+"/history", "1", ""); rr.Code != http.StatusOK {
+		t.Errorf("Задача в корзине: ожидался статус %v, получен %v", http.StatusOK, rr.Code)
+	}
+	do("POST", url+"/restore", "1", "")
+
+	if rr := do("GET", url+"/history", "2", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Чужая задача: ожидался статус %v, получен %v", http.StatusNotFound, rr.Code)
+	}
+
+	rr := do("GET", url+"/history", "1", "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус %v, получен %v", http.StatusOK, rr.Code)
+	}
+	var history []models.TaskHistory
+	if err := json.NewDecoder(rr.Body).Decode(&history); err != nil {
+		t.Fatalf("Ошибка десериализации: %v", err)
+	}
+	wantActions := []string{models.HistoryCreate, models.HistoryUpdate, models.HistoryDelete, models.HistoryRestore}
+	if len(history) != len(wantActions) {
+		t.Fatalf("Ожидалось %d записей истории, получено %d", len(wantActions), len(history))
+	}
+	for i, h := range history {
+		if h.Action != wantActions[i] {
+			t.Errorf("Запись %d: ожидалось действие %q, получено %q", i, wantActions[i], h.Action)
+		}
+		if h.UserID != 1 {
+			t.Errorf("Запись %d: ожидался автор 1, получен %d", i, h.UserID)
+		}
+	}
+
+	if got := string(history[0].Changes["tags"].New); got != `["work"]` {
+		t.Errorf("Создание: ожидались метки [\"work\"], получено %s", got)
+	}
+	update := history[1].Changes
+	// done выводится из статуса, поэтому меняются оба поля
+	if len(update) != 2 {
+		t.Errorf("Ожидалось два изменённых поля, получено %d: %v", len(update), update)
+	}
+	if change := update["done"]; string(change.Old) != "false" || string(change.New) != "true" {
+		t.Errorf("Ожидалось изменение done false → true, получено %s → %s", change.Old, change.New)
+	}
+	if change := update["status"]; string(change.Old) != `"todo"` || string(change.New) != `"done"` {
+		t.Errorf("Ожидалось изменение status todo → done, получено %s → %s", change.Old, change.New)
+	}
+}
+
+func TestTaskHistory_SurvivesPurge(t *testing.T) {
+	schemaName := db.InitTestDB()
+	defer db.DB.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schemaName))
+
+	task := createTask(t, 1, `{"title":"Purged task"}`)
+	req, _ := http.NewRequest("DELETE", fmt.Sprintf("/tasks/%d", task.ID), nil)
+	req.Header.Set("UserID", "1")
+	req.Header.Set("Role", models.RoleUser)
+	handlers.TaskHandler(httptest.NewRecorder(), req)
+
+	if _, err := handlers.PurgeTrash(0); err != nil {
+		t.Fatalf("Ошибка очистки корзины: %v", err)
+	}
+	var count int64
+	db.DB.Model(&models.TaskHistory{}).Where("task_id = ?", task.ID).Count(&count)
+	if count != 2 {
+		t.Errorf("Ожидалось 2 записи истории после очистки корзины, получено %d", count)
+	}
+
+	// Историю удалённой задачи видят её владелец и администратор
+	tests := []struct {
+		name       string
+		userID     string
+		role       string
+		wantStatus int
+	}{
+		{"Владелец", "1", models.RoleUser, http.StatusOK},
+		{"Администратор", "3", models.RoleAdmin, http.StatusOK},
+		{"Посторонний", "2", models.RoleUser, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", fmt.Sprintf("/tasks/%d/history", task.ID), nil)
+			req.Header.Set("UserID", tt.userID)
+			req.Header.Set("Role", tt.role)
+			rr := httptest.NewRecorder()
+
+			handlers.TaskHandler(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("Ожидался статус %v, получен %v", tt.wantStatus, rr.Code)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var history []models.TaskHistory
+			json.NewDecoder(rr.Body).Decode(&history)
+			if len(history) != 2 {
+				t.Errorf("Ожидалось 2 записи истории, получено %d", len(history))
+			}
+		})
+	}
+}
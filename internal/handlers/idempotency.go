@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+	"todo-api/pkg/logger"
+)
+
+const (
+	defaultIdempotencyTTL = 24 * time.Hour
+	// Срок, на который ключ занимается выполняемым запросом. Если процесс
+	// упал, не дописав ответ, ключ освободится сам задолго до IDEMPOTENCY_TTL.
+	idempotencyLease = time.Minute
+	// Максимальная длина заголовка Idempotency-Key
+	maxIdempotencyKeyLength = 255
+)
+
+// idempotencyRecord — запрос с ключом идемпотентности и его ответ в Redis.
+// Пока запрос выполняется, ответа нет и Pending равен true.
+type idempotencyRecord struct {
+	Fingerprint string          `json:"fingerprint"`
+	Pending     bool            `json:"pending,omitempty"`
+	Status      int             `json:"status,omitempty"`
+	ETag        string          `json:"etag,omitempty"`
+	Body        json.RawMessage `json:"body,omitempty"`
+}
+
+// idempotentRequest — занятый ключ идемпотентности текущего запроса
+type idempotentRequest struct {
+	key         string
+	fingerprint string
+}
+
+// startIdempotentRequest обрабатывает заголовок Idempotency-Key. Повтор
+// выполненного запроса получает сохранённый ответ, повтор с другим телом —
+// 422, а одновременный повтор — 409; в этих случаях handled равен true.
+// Без заголовка возвращается nil. Время хранения ответов задаётся
+// переменной IDEMPOTENCY_TTL. Тело сравнивается как JSON: порядок ключей
+// и пробелы на совпадение запросов не влияют.
+func startIdempotentRequest(ctx context.Context, w http.ResponseWriter, r *http.Request, userID int, body []byte) (req *idempotentRequest, handled bool) {
+	key := r.Header.Get("Idempotency-Key")
+	if key == "" {
+		return nil, false
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		http.Error(w, fmt.Sprintf("Idempotency-Key длиннее %d символов", maxIdempotencyKeyLength), http.StatusBadRequest)
+		return nil, true
+	}
+	sum := sha256.Sum256(append([]byte(r.Method+" "+r.URL.Path+"\n"), canonicalJSON(body)...))
+	req = &idempotentRequest{
+		key:         fmt.Sprintf("idempotency:%d:%s", userID, key),
+		fingerprint: hex.EncodeToString(sum[:]),
+	}
+
+	pending, _ := json.Marshal(idempotencyRecord{Fingerprint: req.fingerprint, Pending: true})
+	reserved, err := redisClient.SetNX(ctx, req.key, pending, idempotencyLease).Result()
+	if err != nil {
+		// Без Redis запрос выполняется как обычный
+		logger.Log.Errorf("Ошибка записи ключа идемпотентности в Redis: %v", err)
+		return nil, false
+	}
+	if reserved {
+		return req, false
+	}
+
+	var rec idempotencyRecord
+	data, err := redisClient.Get(ctx, req.key).Bytes()
+	if err == nil {
+		err = json.Unmarshal(data, &rec)
+	}
+	switch {
+	case err != nil:
+		// Ключ мог истечь между SetNX и Get — клиенту достаточно повторить запрос
+		logger.Log.Errorf("Ошибка чтения ключа идемпотентности из Redis: %v", err)
+		http.Error(w, "Запрос с этим Idempotency-Key ещё выполняется", http.StatusConflict)
+	case rec.Fingerprint != req.fingerprint:
+		http.Error(w, "Idempotency-Key уже использован с другим запросом", http.StatusUnprocessableEntity)
+	case rec.Pending:
+		http.Error(w, "Запрос с этим Idempotency-Key ещё выполняется", http.StatusConflict)
+	default:
+		logger.Log.Infof("Повтор запроса с Idempotency-Key %q", key)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Idempotent-Replayed", "true")
+		if rec.ETag != "" {
+			w.Header().Set("ETag", rec.ETag)
+		}
+		w.WriteHeader(rec.Status)
+		w.Write(rec.Body)
+	}
+	return nil, true
+}
+
+// canonicalJSON приводит тело запроса к каноническому виду: ключи объектов
+// по алфавиту, без лишних пробелов, числа в исходной записи. Тело, которое
+// не разбирается как JSON, возвращается как есть.
+func canonicalJSON(body []byte) []byte {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		return body
+	}
+	canonical, err := json.Marshal(v)
+	if err != nil {
+		return body
+	}
+	return canonical
+}
+
+// complete сохраняет ответ для повторов с тем же ключом на полный срок
+// IDEMPOTENCY_TTL
+func (req *idempotentRequest) complete(ctx context.Context, status int, etag string, body []byte) {
+	if req == nil {
+		return
+	}
+	data, _ := json.Marshal(idempotencyRecord{Fingerprint: req.fingerprint, Status: status, ETag: etag, Body: body})
+	if err := redisClient.Set(ctx, req.key, data, durationEnv("IDEMPOTENCY_TTL", defaultIdempotencyTTL)).Err(); err != nil {
+		logger.Log.Errorf("Ошибка записи ответа в Redis: %v", err)
+	}
+}
+
+// release освобождает ключ после неудачного запроса, чтобы его можно было
+// повторить
+func (req *idempotentRequest) release(ctx context.Context) {
+	if req == nil {
+		return
+	}
+	if err := redisClient.Del(ctx, req.key).Err(); err != nil {
+		logger.Log.Errorf("Ошибка удаления ключа идемпотентности из Redis: %v", err)
+	}
+}
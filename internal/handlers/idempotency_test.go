@@ -0,0 +1,70 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"todo-api/internal/handlers"
+	"todo-api/internal/models"
+	"todo-api/pkg/db"
+)
+
+func TestTasksHandler_Post_IdempotencyKey(t *testing.T) {
+	schemaName := db.InitTestDB()
+	defer db.DB.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schemaName))
+
+	// Ключи живут в Redis дольше тестовой схемы, поэтому делаем их уникальными
+	key := fmt.Sprintf("test-%d", time.Now().UnixNano())
+
+	post := func(userID, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/tasks", strings.NewReader(body))
+		req.Header.Set("UserID", userID)
+		req.Header.Set("Role", models.RoleUser)
+		req.Header.Set("Idempotency-Key", key)
+		rr := httptest.NewRecorder()
+		handlers.TasksHandler(rr, req)
+		return rr
+	}
+
+	first := post("1", `{"title":"Buy milk"}`)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("Ожидался статус %v, получен %v", http.StatusCreated, first.Code)
+	}
+	retry := post("1", `{"title":"Buy milk"}`)
+	if retry.Code != http.StatusCreated {
+		t.Fatalf("Повтор: ожидался статус %v, получен %v", http.StatusCreated, retry.Code)
+	}
+	if retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("Повтор не помечен заголовком Idempotent-Replayed")
+	}
+	if retry.Body.String() != first.Body.String() {
+		t.Errorf("Повтор вернул другой ответ: %s, ожидался %s", retry.Body.String(), first.Body.String())
+	}
+	// Тот же JSON в другой записи считается тем же запросом
+	if rr := post("1", `{ "title" : "Buy milk" }`); rr.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("Переформатированное тело: ожидался повтор, получен статус %v", rr.Code)
+	}
+
+	var count int64
+	db.DB.Model(&models.Task{}).Where("user_id = 1").Count(&count)
+	if count != 1 {
+		t.Errorf("Ожидалась 1 задача, создано %d", count)
+	}
+
+	if rr := post("1", `{"title":"Buy bread"}`); rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Другое тело: ожидался статус %v, получен %v", http.StatusUnprocessableEntity, rr.Code)
+	}
+
+	// Ключи разных пользователей не пересекаются
+	other := post("2", `{"title":"Buy milk"}`)
+	var a, b models.Task
+	json.Unmarshal(first.Body.Bytes(), &a)
+	json.Unmarshal(other.Body.Bytes(), &b)
+	if other.Code != http.StatusCreated || a.ID == b.ID {
+		t.Errorf("Другой пользователь: ожидалась новая задача, получен статус %v", other.Code)
+	}
+}
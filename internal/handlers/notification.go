@@ -7,10 +7,12 @@ import (
 	"todo-api/pkg/logger"
 )
 
-func notifyUser(ctx context.Context, userID int, taskTitle string, ch chan<- string) {
+// notifyUser уведомляет пользователя о событии с задачей, например
+// "создана" или "назначена вам"
+func notifyUser(ctx context.Context, userID int, taskTitle, event string, ch chan<- string) {
 	select {
 	case <-time.After(2 * time.Second):
-		logger.Log.Infof("Уведомление отправлено пользователю %d: задача '%s' создана", userID, taskTitle)
+		logger.Log.Infof("Уведомление отправлено пользователю %d: задача '%s' %s", userID, taskTitle, event)
 		ch <- fmt.Sprintf("Notification sent for task '%s'", taskTitle)
 	case <-ctx.Done():
 		logger.Log.Warnf("Уведомление для пользователя %d отменено: %v", userID, ctx.Err())
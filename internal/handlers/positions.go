@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"todo-api/internal/models"
+	"todo-api/pkg/db"
+	"todo-api/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// Шаг между соседними задачами в конце списка и после перебалансировки
+	positionStep = 1024
+	// Если промежуток между соседями стал меньше, порядок перебалансируется
+	// в фоне, пока точности float64 ещё хватает на новые перемещения
+	positionRebalanceGap = 1e-6
+)
+
+// moveRequest — тело POST /tasks/{id}/move. Задаётся ровно один ориентир:
+// задача, перед которой или после которой нужно поставить перемещаемую.
+type moveRequest struct {
+	Before *int `json:"before"`
+	After  *int `json:"after"`
+}
+
+// Обработчик ручного порядка задач: POST /tasks/{id}/move.
+// Меняется позиция только перемещаемой задачи — её ставят посередине между
+// ориентиром и его соседом. Порядок свой у каждого проекта и у личных задач
+// каждого владельца, поэтому ориентир должен быть из того же списка.
+func moveTaskHandler(w http.ResponseWriter, r *http.Request, id, userID int, role string) {
+	if r.Method != "POST" {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+	var req moveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Некорректный запрос", http.StatusBadRequest)
+		return
+	}
+	if (req.Before == nil) == (req.After == nil) {
+		http.Error(w, "Укажите ровно один из параметров before или after", http.StatusBadRequest)
+		return
+	}
+	anchorID, after := req.Before, false
+	if req.After != nil {
+		anchorID, after = req.After, true
+	}
+	if *anchorID == id {
+		http.Error(w, "Задачу нельзя переместить относительно самой себя", http.StatusBadRequest)
+		return
+	}
+
+	var t models.Task
+	var gap float64
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var ok bool
+		if t, ok = findTaskIn(tx, id, userID, role); !ok {
+			return newHTTPError(http.StatusNotFound, "Задача не найдена")
+		}
+		if taskAccess(t, userID, role) < accessEdit {
+			return newHTTPError(http.StatusForbidden, "Недостаточно прав")
+		}
+		if err := checkIfMatch(r, t); err != nil {
+			return err
+		}
+		anchor, ok := findTaskIn(tx, *anchorID, userID, role)
+		if !ok {
+			return newHTTPError(http.StatusBadRequest, "Задача %d не найдена", *anchorID)
+		}
+		if !sameTaskList(anchor, t) {
+			return newHTTPError(http.StatusBadRequest, "Ручной порядок задаётся только внутри одного проекта или среди личных задач одного владельца")
+		}
+
+		position, g, ok, err := positionNear(tx, t, anchor, after)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			// Между соседями не осталось места: раздвигаем весь список и
+			// повторяем расчёт с новыми позициями
+			if err := rebalancePositions(tx, t); err != nil {
+				return err
+			}
+			if err := tx.Select("position").First(&anchor, anchor.ID).Error; err != nil {
+				return err
+			}
+			if err := tx.Select("position", "version").First(&t, t.ID).Error; err != nil {
+				return err
+			}
+			if position, g, _, err = positionNear(tx, t, anchor, after); err != nil {
+				return err
+			}
+		}
+		gap = g
+		t.Position = position
+		return saveTaskVersion(tx, &t, t.Version, "position")
+	})
+	if err != nil {
+		writeError(w, err, "Ошибка перемещения задачи")
+		return
+	}
+	InvalidateTaskLists()
+	if gap < positionRebalanceGap {
+		go func(t models.Task) {
+			if err := db.DB.Transaction(func(tx *gorm.DB) error {
+				return rebalancePositions(tx, t)
+			}); err != nil {
+				logger.Log.Errorf("Ошибка перебалансировки порядка задач: %v", err)
+				return
+			}
+			InvalidateTaskLists()
+		}(t)
+	}
+	w.Header().Set("ETag", taskETag(t))
+	json.NewEncoder(w).Encode(t)
+}
+
+// positionNear вычисляет позицию задачи t рядом с anchor: середину
+// промежутка между ориентиром и его соседом, а на краю списка — на шаг
+// дальше ориентира. ok равен false, если между соседями не осталось
+// различимых позиций.
+func positionNear(tx *gorm.DB, t, anchor models.Task, after bool) (position, gap float64, ok bool, err error) {
+	neighbor := positionScope(tx.Model(&models.Task{}), anchor).
+		Where("id <> ?", t.ID).Limit(1)
+	if after {
+		neighbor = neighbor.Where("(position > ? OR (position = ? AND id > ?))", anchor.Position, anchor.Position, anchor.ID).
+			Order("position, id")
+	} else {
+		neighbor = neighbor.Where("(position < ? OR (position = ? AND id < ?))", anchor.Position, anchor.Position, anchor.ID).
+			Order("position DESC, id DESC")
+	}
+	var positions []float64
+	if err := neighbor.Pluck("position", &positions).Error; err != nil {
+		return 0, 0, false, err
+	}
+
+	if len(positions) == 0 {
+		if after {
+			return anchor.Position + positionStep, positionStep, true, nil
+		}
+		return anchor.Position - positionStep, positionStep, true, nil
+	}
+	lo, hi := positions[0], anchor.Position
+	if after {
+		lo, hi = anchor.Position, positions[0]
+	}
+	position = lo + (hi-lo)/2
+	gap = (hi - lo) / 2
+	return position, gap, lo < position && position < hi, nil
+}
+
+// positionScope ограничивает запрос списком ручного порядка задачи t:
+// задачами её проекта, а для задачи без проекта — личными задачами владельца
+func positionScope(query *gorm.DB, t models.Task) *gorm.DB {
+	if t.ProjectID != nil {
+		return query.Where("project_id = ?", *t.ProjectID)
+	}
+	return query.Where("user_id = ? AND project_id IS NULL", t.UserID)
+}
+
+// sameTaskList сообщает, относятся ли задачи к одному списку ручного порядка
+func sameTaskList(a, b models.Task) bool {
+	if a.ProjectID != nil || b.ProjectID != nil {
+		return a.ProjectID != nil && b.ProjectID != nil && *a.ProjectID == *b.ProjectID
+	}
+	return a.UserID == b.UserID
+}
+
+// nextTaskPosition возвращает позицию в конце списка ручного порядка, в
+// который попадает задача t
+func nextTaskPosition(tx *gorm.DB, t models.Task) (float64, error) {
+	var last float64
+	err := positionScope(tx.Unscoped().Model(&models.Task{}), t).
+		Select("COALESCE(MAX(position), 0)").
+		Scan(&last).Error
+	return last + positionStep, err
+}
+
+// rebalancePositions расставляет задачи списка, в котором стоит t, с равным
+// шагом, сохраняя их порядок. Задачи в корзине тоже участвуют, чтобы после
+// восстановления вернуться на своё место. Версия растёт только у сдвинутых
+// задач.
+func rebalancePositions(tx *gorm.DB, t models.Task) error {
+	ranked := positionScope(tx.Unscoped().Model(&models.Task{}), t).
+		Select("id, row_number() OVER (ORDER BY position, id) AS n")
+	return tx.Exec(`UPDATE tasks SET position = ranked.n * ?, version = version + 1
+		FROM (?) AS ranked
+		WHERE tasks.id = ranked.id AND tasks.position <> ranked.n * ?`,
+		positionStep, ranked, positionStep).Error
+}
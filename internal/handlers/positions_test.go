@@ -0,0 +1,141 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"todo-api/internal/handlers"
+	"todo-api/internal/models"
+	"todo-api/pkg/db"
+)
+
+func moveTask(id int, userID, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/tasks/%d/move", id), strings.NewReader(body))
+	req.Header.Set("UserID", userID)
+	req.Header.Set("Role", models.RoleUser)
+	rr := httptest.NewRecorder()
+	handlers.TaskHandler(rr, req)
+	return rr
+}
+
+// manualOrder возвращает заголовки задач пользователя в ручном порядке
+func manualOrder(userID int) []string {
+	var titles []string
+	db.DB.Model(&models.Task{}).Where("user_id = ?", userID).Order("position, id").Pluck("title", &titles)
+	return titles
+}
+
+func TestMoveTask(t *testing.T) {
+	schemaName := db.InitTestDB()
+	defer db.DB.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schemaName))
+
+	a := createTask(t, 1, `{"title":"Task A"}`)
+	b := createTask(t, 1, `{"title":"Task B"}`)
+	c := createTask(t, 1, `{"title":"Task C"}`)
+	other := createTask(t, 2, `{"title":"Task D"}`)
+
+	tests := []struct {
+		name       string
+		id         int
+		body       string
+		wantStatus int
+		wantOrder  []string
+	}{
+		{"В начало списка", c.ID, fmt.Sprintf(`{"before":%d}`, a.ID), http.StatusOK, []string{"Task C", "Task A", "Task B"}},
+		{"Между задачами", b.ID, fmt.Sprintf(`{"after":%d}`, c.ID), http.StatusOK, []string{"Task C", "Task B", "Task A"}},
+		{"В конец списка", c.ID, fmt.Sprintf(`{"after":%d}`, a.ID), http.StatusOK, []string{"Task B", "Task A", "Task C"}},
+		{"Два ориентира", a.ID, fmt.Sprintf(`{"before":%d,"after":%d}`, b.ID, c.ID), http.StatusBadRequest, nil},
+		{"Без ориентира", a.ID, `{}`, http.StatusBadRequest, nil},
+		{"Относительно себя", a.ID, fmt.Sprintf(`{"after":%d}`, a.ID), http.StatusBadRequest, nil},
+		{"Чужой ориентир", a.ID, fmt.Sprintf(`{"after":%d}`, other.ID), http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		rr := moveTask(tt.id, "1", tt.body)
+		if rr.Code != tt.wantStatus {
+			t.Errorf("%s: ожидался статус %v, получен %v: %s", tt.name, tt.wantStatus, rr.Code, rr.Body.String())
+			continue
+		}
+		if tt.wantOrder != nil && !reflect.DeepEqual(manualOrder(1), tt.wantOrder) {
+			t.Errorf("%s: ожидался порядок %v, получен %v", tt.name, tt.wantOrder, manualOrder(1))
+		}
+	}
+
+	if rr := moveTask(other.ID, "1", fmt.Sprintf(`{"after":%d}`, a.ID)); rr.Code != http.StatusNotFound {
+		t.Errorf("Чужая задача: ожидался статус %v, получен %v", http.StatusNotFound, rr.Code)
+	}
+}
+
+func TestMoveTask_Rebalance(t *testing.T) {
+	schemaName := db.InitTestDB()
+	defer db.DB.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schemaName))
+
+	// У задач без ручного порядка позиции совпадают, места между ними нет
+	tasks := SeedTasks(4)
+
+	rr := moveTask(tasks[3].ID, "1", fmt.Sprintf(`{"after":%d}`, tasks[0].ID))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус %v, получен %v: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	want := []string{"Task A", "Task D", "Task B", "Task C"}
+	if got := manualOrder(1); !reflect.DeepEqual(got, want) {
+		t.Errorf("Ожидался порядок %v, получен %v", want, got)
+	}
+	var count int64
+	db.DB.Model(&models.Task{}).Where("user_id = 1 AND position = 0").Count(&count)
+	if count != 0 {
+		t.Errorf("После перебалансировки осталось %d задач без позиции", count)
+	}
+}
+
+func TestMoveTask_Project(t *testing.T) {
+	schemaName := initTestDB()
+	defer db.DB.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schemaName))
+
+	project := models.Project{Name: "Backend", OwnerID: 1, Members: []models.ProjectMember{
+		{UserID: 1, Role: models.ProjectRoleOwner},
+		{UserID: 2, Role: models.ProjectRoleEditor},
+	}}
+	db.DB.Create(&project)
+
+	a := createTask(t, 1, fmt.Sprintf(`{"title":"Task A","project_id":%d}`, project.ID))
+	b := createTask(t, 2, fmt.Sprintf(`{"title":"Task B","project_id":%d}`, project.ID))
+	personal := createTask(t, 1, `{"title":"Personal task"}`)
+	later := createTask(t, 1, `{"title":"Later task"}`)
+
+	// Задачи разных владельцев в одном проекте упорядочиваются вместе
+	if rr := moveTask(b.ID, "1", fmt.Sprintf(`{"before":%d}`, a.ID)); rr.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус %v, получен %v: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	// Личные задачи упорядочиваются отдельно от проекта
+	if rr := moveTask(later.ID, "1", fmt.Sprintf(`{"before":%d}`, personal.ID)); rr.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус %v, получен %v: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	for query, want := range map[string][]string{
+		fmt.Sprintf("?project_id=%d", project.ID): {"Task B", "Task A"},
+		"?project_id=none":                        {"Later task", "Personal task"},
+	} {
+		req, _ := http.NewRequest("GET", "/tasks"+query, nil)
+		req.Header.Set("UserID", "1")
+		req.Header.Set("Role", models.RoleUser)
+		rr := httptest.NewRecorder()
+		handlers.TasksHandler(rr, req)
+		var tasks []models.Task
+		json.NewDecoder(rr.Body).Decode(&tasks)
+		var titles []string
+		for _, task := range tasks {
+			titles = append(titles, task.Title)
+		}
+		if !reflect.DeepEqual(titles, want) {
+			t.Errorf("%s: ожидался порядок %v, получен %v", query, want, titles)
+		}
+	}
+
+	// Личная задача стоит в другом списке
+	if rr := moveTask(personal.ID, "1", fmt.Sprintf(`{"after":%d}`, a.ID)); rr.Code != http.StatusBadRequest {
+		t.Errorf("Другой список: ожидался статус %v, получен %v", http.StatusBadRequest, rr.Code)
+	}
+}
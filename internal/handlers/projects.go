@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"todo-api/internal/models"
+	"todo-api/pkg/db"
+	"todo-api/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// Обработчик для списка проектов
+func ProjectsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, _ := strconv.Atoi(r.Header.Get("UserID"))
+	role := r.Header.Get("Role")
+
+	switch r.Method {
+	case "GET":
+		query := db.DB.Preload("Members").Order("id")
+		if role != models.RoleAdmin {
+			query = query.Where("id IN (?)", memberProjectIDs(userID))
+		}
+		projects := []models.Project{}
+		if err := query.Find(&projects).Error; err != nil {
+			logger.Log.Errorf("Ошибка получения проектов: %v", err)
+			http.Error(w, "Ошибка получения проектов", http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(projects)
+	case "POST":
+		var p models.Project
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			http.Error(w, "Некорректный запрос", http.StatusBadRequest)
+			return
+		}
+		p.ID = 0
+		p.OwnerID = userID
+		if err := validate.Struct(p); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Создатель проекта становится его владельцем
+		err := db.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Omit("Members").Create(&p).Error; err != nil {
+				return err
+			}
+			owner := models.ProjectMember{ProjectID: p.ID, UserID: userID, Role: models.ProjectRoleOwner}
+			if err := tx.Create(&owner).Error; err != nil {
+				return err
+			}
+			p.Members = []models.ProjectMember{owner}
+			return nil
+		})
+		if err != nil {
+			writeError(w, err, "Ошибка создания проекта")
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(p)
+	default:
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+	}
+}
+
+// Обработчик для конкретного проекта и его участников
+func ProjectHandler(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path[len("/projects/"):], "/"), "/")
+	id, err := strconv.Atoi(parts[0])
+	if err != nil {
+		http.Error(w, "Некорректный ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := strconv.Atoi(r.Header.Get("UserID"))
+	role := r.Header.Get("Role")
+
+	var p models.Project
+	if err := db.DB.Preload("Members").First(&p, id).Error; err != nil {
+		http.Error(w, "Проект не найден", http.StatusNotFound)
+		return
+	}
+	// Чужой проект для пользователя неотличим от несуществующего
+	memberRole := p.MemberRole(userID)
+	if memberRole == "" && role != models.RoleAdmin {
+		http.Error(w, "Проект не найден", http.StatusNotFound)
+		return
+	}
+	isOwner := memberRole == models.ProjectRoleOwner || role == models.RoleAdmin
+
+	if len(parts) > 1 {
+		if parts[1] != "members" {
+			http.NotFound(w, r)
+			return
+		}
+		projectMembersHandler(w, r, p, parts[2:], userID, isOwner)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		json.NewEncoder(w).Encode(p)
+	case "PUT":
+		if !isOwner {
+			http.Error(w, "Недостаточно прав", http.StatusForbidden)
+			return
+		}
+		var input models.Project
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Некорректный запрос", http.StatusBadRequest)
+			return
+		}
+		if err := validate.Struct(input); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		p.Name = input.Name
+		if err := db.DB.Model(&p).Update("name", p.Name).Error; err != nil {
+			writeError(w, err, "Ошибка обновления проекта")
+			return
+		}
+		json.NewEncoder(w).Encode(p)
+	case "DELETE":
+		if !isOwner {
+			http.Error(w, "Недостаточно прав", http.StatusForbidden)
+			return
+		}
+		// Задачи проекта, включая лежащие в корзине, остаются у своих создателей
+		err := db.DB.Transaction(func(tx *gorm.DB) error {
+			var taskIDs []int
+			if err := tx.Unscoped().Model(&models.Task{}).Where("project_id = ?", p.ID).Pluck("id", &taskIDs).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Model(&models.Task{}).Where("id IN ?", taskIDs).Updates(map[string]interface{}{"project_id": nil, "version": bumpVersion}).Error; err != nil {
+				return err
+			}
+			changes, err := fieldChange("project_id", p.ID, nil)
+			if err != nil {
+				return err
+			}
+			if err := recordTasksAction(tx, taskIDs, userID, models.HistoryUpdate, changes); err != nil {
+				return err
+			}
+			if err := tx.Where("project_id = ?", p.ID).Delete(&models.ProjectMember{}).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&models.Project{}, p.ID).Error
+		})
+		if err != nil {
+			writeError(w, err, "Ошибка удаления проекта")
+			return
+		}
+		InvalidateTaskLists()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+	}
+}
+
+// Обработчик для участников проекта: /projects/{id}/members[/{user_id}]
+func projectMembersHandler(w http.ResponseWriter, r *http.Request, p models.Project, rest []string, userID int, isOwner bool) {
+	if len(rest) == 0 {
+		switch r.Method {
+		case "GET":
+			json.NewEncoder(w).Encode(p.Members)
+		case "POST":
+			if !isOwner {
+				http.Error(w, "Недостаточно прав", http.StatusForbidden)
+				return
+			}
+			var m models.ProjectMember
+			if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+				http.Error(w, "Некорректный запрос", http.StatusBadRequest)
+				return
+			}
+			m.ProjectID = p.ID
+			if err := validate.Struct(m); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if p.MemberRole(m.UserID) != "" {
+				http.Error(w, "Пользователь уже участник проекта", http.StatusConflict)
+				return
+			}
+			if err := db.DB.Create(&m).Error; err != nil {
+				writeError(w, err, "Ошибка добавления участника")
+				return
+			}
+			InvalidateTaskLists()
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(m)
+		default:
+			http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	memberID, err := strconv.Atoi(rest[0])
+	if err != nil {
+		http.Error(w, "Некорректный ID участника", http.StatusBadRequest)
+		return
+	}
+	current := p.MemberRole(memberID)
+	if current == "" {
+		http.Error(w, "Участник не найден", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case "PUT":
+		if !isOwner {
+			http.Error(w, "Недостаточно прав", http.StatusForbidden)
+			return
+		}
+		var m models.ProjectMember
+		if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+			http.Error(w, "Некорректный запрос", http.StatusBadRequest)
+			return
+		}
+		m.ProjectID = p.ID
+		m.UserID = memberID
+		if err := validate.Struct(m); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if current == models.ProjectRoleOwner && m.Role != models.ProjectRoleOwner && p.OwnerCount() == 1 {
+			http.Error(w, "В проекте должен остаться хотя бы один владелец", http.StatusConflict)
+			return
+		}
+		if err := db.DB.Model(&models.ProjectMember{}).Where("project_id = ? AND user_id = ?", p.ID, memberID).Update("role", m.Role).Error; err != nil {
+			writeError(w, err, "Ошибка обновления участника")
+			return
+		}
+		InvalidateTaskLists()
+		json.NewEncoder(w).Encode(m)
+	case "DELETE":
+		// Покинуть проект может любой участник, исключить — только владелец
+		if !isOwner && memberID != userID {
+			http.Error(w, "Недостаточно прав", http.StatusForbidden)
+			return
+		}
+		if current == models.ProjectRoleOwner && p.OwnerCount() == 1 {
+			http.Error(w, "В проекте должен остаться хотя бы один владелец", http.StatusConflict)
+			return
+		}
+		if err := db.DB.Where("project_id = ? AND user_id = ?", p.ID, memberID).Delete(&models.ProjectMember{}).Error; err != nil {
+			writeError(w, err, "Ошибка удаления участника")
+			return
+		}
+		InvalidateTaskLists()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+	}
+}
+
+// memberProjectIDs — подзапрос с проектами, в которых участвует пользователь
+func memberProjectIDs(userID int, roles ...string) *gorm.DB {
+	query := db.DB.Model(&models.ProjectMember{}).Select("project_id").Where("user_id = ?", userID)
+	if len(roles) > 0 {
+		query = query.Where("role IN ?", roles)
+	}
+	return query
+}
+
+// projectRole возвращает роль пользователя в проекте или пустую строку
+func projectRole(tx *gorm.DB, projectID, userID int) (string, error) {
+	var m models.ProjectMember
+	err := tx.Where("project_id = ? AND user_id = ?", projectID, userID).Limit(1).Find(&m).Error
+	return m.Role, err
+}
@@ -0,0 +1,116 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"todo-api/internal/handlers"
+	"todo-api/internal/models"
+	"todo-api/pkg/db"
+)
+
+func TestProjects_MemberRoles(t *testing.T) {
+	schemaName := db.InitTestDB()
+	defer db.DB.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schemaName))
+
+	// Пользователь 1 создаёт проект и становится владельцем
+	req, _ := http.NewRequest("POST", "/projects", strings.NewReader(`{"name":"Backend"}`))
+	req.Header.Set("UserID", "1")
+	rr := httptest.NewRecorder()
+	handlers.ProjectsHandler(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Ожидался статус %v, получен %v: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+	var project models.Project
+	if err := json.NewDecoder(rr.Body).Decode(&project); err != nil {
+		t.Fatalf("Ошибка десериализации ответа: %v", err)
+	}
+
+	// Пользователь 2 — наблюдатель, пользователь 3 — редактор
+	for _, member := range []string{`{"user_id":2,"role":"viewer"}`, `{"user_id":3,"role":"editor"}`} {
+		req, _ := http.NewRequest("POST", fmt.Sprintf("/projects/%d/members", project.ID), strings.NewReader(member))
+		req.Header.Set("UserID", "1")
+		rr := httptest.NewRecorder()
+		handlers.ProjectHandler(rr, req)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("Ожидался статус %v, получен %v: %s", http.StatusCreated, rr.Code, rr.Body.String())
+		}
+	}
+
+	task := createTask(t, 1, fmt.Sprintf(`{"title":"Shared task","project_id":%d}`, project.ID))
+	url := fmt.Sprintf("/tasks/%d", task.ID)
+
+	tests := []struct {
+		name       string
+		method     string
+		userID     string
+		body       string
+		wantStatus int
+	}{
+		{"Наблюдатель читает", "GET", "2", "", http.StatusOK},
+		{"Наблюдатель не может менять", "PATCH", "2", `{"title":"By viewer"}`, http.StatusForbidden},
+		{"Наблюдатель не может удалять", "DELETE", "2", "", http.StatusForbidden},
+		{"Редактор меняет", "PATCH", "3", `{"title":"By editor"}`, http.StatusOK},
+		{"Посторонний не видит", "GET", "4", "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, url, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/merge-patch+json")
+			req.Header.Set("UserID", tt.userID)
+			req.Header.Set("Role", models.RoleUser)
+			rr := httptest.NewRecorder()
+
+			handlers.TaskHandler(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("Ожидался статус %v, получен %v", tt.wantStatus, rr.Code)
+			}
+		})
+	}
+
+	// Наблюдатель не может добавлять задачи в проект
+	req, _ = http.NewRequest("POST", "/tasks", strings.NewReader(fmt.Sprintf(`{"title":"Viewer task","project_id":%d}`, project.ID)))
+	req.Header.Set("UserID", "2")
+	rr = httptest.NewRecorder()
+	handlers.TasksHandler(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("Ожидался статус %v, получен %v", http.StatusForbidden, rr.Code)
+	}
+
+	// Задачи проекта видны участникам в общем списке
+	req, _ = http.NewRequest("GET", fmt.Sprintf("/tasks?project_id=%d", project.ID), nil)
+	req.Header.Set("UserID", "2")
+	req.Header.Set("Role", models.RoleUser)
+	rr = httptest.NewRecorder()
+	handlers.TasksHandler(rr, req)
+	var tasks []models.Task
+	if err := json.NewDecoder(rr.Body).Decode(&tasks); err != nil {
+		t.Fatalf("Ошибка десериализации ответа: %v", err)
+	}
+	if len(tasks) != 1 {
+		t.Errorf("Ожидалась 1 задача, получено %d", len(tasks))
+	}
+}
+
+func TestProjects_LastOwnerCannotLeave(t *testing.T) {
+	schemaName := db.InitTestDB()
+	defer db.DB.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schemaName))
+
+	project := models.Project{Name: "Solo", OwnerID: 1}
+	db.DB.Omit("Members").Create(&project)
+	db.DB.Create(&models.ProjectMember{ProjectID: project.ID, UserID: 1, Role: models.ProjectRoleOwner})
+
+	req, _ := http.NewRequest("DELETE", fmt.Sprintf("/projects/%d/members/1", project.ID), nil)
+	req.Header.Set("UserID", "1")
+	rr := httptest.NewRecorder()
+
+	handlers.ProjectHandler(rr, req)
+
+	if rr.Code != http.StatusConflict {
+		t.Errorf("Ожидался статус %v, получен %v", http.StatusConflict, rr.Code)
+	}
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+	"todo-api/internal/models"
+	"todo-api/pkg/logger"
+	"todo-api/pkg/rrule"
+
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+// Сколько вхождений можно запросить за раз
+const maxOccurrencesPreview = 100
+
+func init() {
+	validate.RegisterValidation("rrule", func(fl validator.FieldLevel) bool {
+		_, err := rrule.Parse(fl.Field().String())
+		return err == nil
+	})
+}
+
+// Обработчик для предпросмотра повторений: GET /tasks/{id}/occurrences?count=N
+func taskOccurrencesHandler(w http.ResponseWriter, r *http.Request, id, userID int, role string) {
+	if r.Method != "GET" {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+	t, ok := findTask(id, userID, role)
+	if !ok {
+		http.Error(w, "Задача не найдена", http.StatusNotFound)
+		return
+	}
+	if t.RRule == "" {
+		http.Error(w, "Задача не повторяется", http.StatusBadRequest)
+		return
+	}
+	count, err := strconv.Atoi(r.URL.Query().Get("count"))
+	if err != nil || count < 1 {
+		count = 5
+	}
+	if count > maxOccurrencesPreview {
+		count = maxOccurrencesPreview
+	}
+
+	rule, err := rrule.Parse(t.RRule)
+	if err != nil {
+		http.Error(w, "Некорректное правило повторения", http.StatusBadRequest)
+		return
+	}
+	start := recurrenceStart(t)
+	occurrences := []models.DueDate{}
+	for _, next := range rule.Next(start, start, count) {
+		occurrences = append(occurrences, models.DueDate{Time: next})
+	}
+	json.NewEncoder(w).Encode(occurrences)
+}
+
+// recurrenceStart — момент, от которого отсчитываются повторения задачи:
+// срок выполнения, а если его нет — текущее время
+func recurrenceStart(t models.Task) time.Time {
+	if t.DueDate != nil {
+		return t.DueDate.Time
+	}
+	return time.Now().UTC().Truncate(time.Second)
+}
+
+// spawnNextOccurrence создаёт следующее вхождение повторяющейся задачи со
+// сроком, перенесённым по правилу. COUNT в копии уменьшается на единицу,
+// поэтому каждая задача серии хранит число оставшихся вхождений.
+func spawnNextOccurrence(tx *gorm.DB, userID int, t models.Task) (*models.Task, error) {
+	rule, err := rrule.Parse(t.RRule)
+	if err != nil {
+		return nil, err
+	}
+	start := recurrenceStart(t)
+	nextTime, ok := rule.After(start, start)
+	if !ok {
+		// Серия закончилась
+		return nil, nil
+	}
+	if rule.Count > 0 {
+		rule.Count--
+	}
+
+	var tags []models.Tag
+	if err := tx.Model(&t).Association("Tags").Find(&tags); err != nil {
+		return nil, err
+	}
+	next := models.Task{
+		Title:        t.Title,
+		Status:       models.StatusTodo,
+		DueDate:      &models.DueDate{Time: nextTime},
+		RRule:        rule.String(),
+		ParentID:     t.ParentID,
+		AutoComplete: t.AutoComplete,
+		ProjectID:    t.ProjectID,
+		UserID:       t.UserID,
+		Version:      1,
+		Position:     t.Position, // Следующее повторение встаёт рядом с текущим
+	}
+	if err := tx.Omit("Tags", "Assignees").Create(&next).Error; err != nil {
+		return nil, err
+	}
+	if err := replaceTaskTags(tx, &next, tags); err != nil {
+		return nil, err
+	}
+	// Следующее повторение достаётся тем же исполнителям
+	var assignees []models.TaskAssignee
+	if err := tx.Where("task_id = ?", t.ID).Find(&assignees).Error; err != nil {
+		return nil, err
+	}
+	if err := replaceTaskAssignees(tx, &next, assignees, userID); err != nil {
+		return nil, err
+	}
+	if err := recordTaskChange(tx, userID, nil, next); err != nil {
+		return nil, err
+	}
+	logger.Log.Infof("Создано следующее вхождение %d повторяющейся задачи %d", next.ID, t.ID)
+	return &next, nil
+}
+
+// afterTaskUpdate выполняет побочные действия изменения задачи в той же
+// транзакции: пишет историю, закрывает родителей и порождает следующее
+// повторение. Повторение создаётся один раз: задача запоминает его в
+// next_task_id, и повторное выполнение после возврата в работу новой
+// копии не порождает.
+func afterTaskUpdate(tx *gorm.DB, userID int, existing models.Task, t *models.Task) error {
+	after := *t
+	if after.Tags == nil {
+		after.Tags = existing.Tags
+	}
+	if after.Assignees == nil {
+		after.Assignees = existing.Assignees
+	}
+	if err := recordTaskChange(tx, userID, &existing, after); err != nil {
+		return err
+	}
+	if err := completeParents(tx, userID, *t); err != nil {
+		return err
+	}
+	if !existing.Done && t.Done && t.RRule != "" && t.NextTaskID == nil {
+		next, err := spawnNextOccurrence(tx, userID, *t)
+		if err != nil || next == nil {
+			return err
+		}
+		t.NextTaskID = &next.ID
+		return tx.Model(&models.Task{}).Where("id = ?", t.ID).UpdateColumn("next_task_id", next.ID).Error
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"todo-api/internal/handlers"
+	"todo-api/internal/models"
+	"todo-api/pkg/db"
+)
+
+func TestRecurringTask_NextOccurrence(t *testing.T) {
+	schemaName := db.InitTestDB()
+	defer db.DB.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schemaName))
+
+	// 6 января 2025 — понедельник
+	task := createTask(t, 1, `{"title":"Weekly standup","due_date":"2025-01-06","rrule":"FREQ=WEEKLY;BYDAY=MO;COUNT=3","tags":["meetings"]}`)
+
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/tasks/%d/occurrences?count=5", task.ID), nil)
+	req.Header.Set("UserID", "1")
+	rr := httptest.NewRecorder()
+	handlers.TaskHandler(rr, req)
+	var occurrences []string
+	if err := json.NewDecoder(rr.Body).Decode(&occurrences); err != nil {
+		t.Fatalf("Ошибка десериализации ответа: %v", err)
+	}
+	// Из трёх вхождений серии первое — сама задача
+	if want := []string{"2025-01-13", "2025-01-20"}; strings.Join(occurrences, ",") != strings.Join(want, ",") {
+		t.Errorf("Ожидались вхождения %v, получено %v", want, occurrences)
+	}
+
+	req, _ = http.NewRequest("PATCH", fmt.Sprintf("/tasks/%d", task.ID), strings.NewReader(`{"done":true}`))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	req.Header.Set("UserID", "1")
+	rr = httptest.NewRecorder()
+	handlers.TaskHandler(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус %v, получен %v: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	var next models.Task
+	if err := db.DB.Preload("Tags").Where("id <> ?", task.ID).First(&next).Error; err != nil {
+		t.Fatalf("Следующее вхождение не создано: %v", err)
+	}
+	if next.Done {
+		t.Error("Следующее вхождение должно быть открытым")
+	}
+	if next.DueDate == nil || next.DueDate.Format("2006-01-02") != "2025-01-13" {
+		t.Errorf("Ожидался срок 2025-01-13, получен %v", next.DueDate)
+	}
+	if next.RRule != "FREQ=WEEKLY;COUNT=2;BYDAY=MO" {
+		t.Errorf("Ожидалось правило с COUNT=2, получено %q", next.RRule)
+	}
+	if len(next.Tags) != 1 || next.Tags[0].Name != "meetings" {
+		t.Errorf("Метки должны переноситься в следующее вхождение, получено %v", next.Tags)
+	}
+
+	// Возврат в работу и повторное выполнение не создают ещё одну копию
+	for _, patch := range []string{`{"done":false}`, `{"done":true}`} {
+		req, _ := http.NewRequest("PATCH", fmt.Sprintf("/tasks/%d", task.ID), strings.NewReader(patch))
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		req.Header.Set("UserID", "1")
+		rr := httptest.NewRecorder()
+		handlers.TaskHandler(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s: ожидался статус %v, получен %v: %s", patch, http.StatusOK, rr.Code, rr.Body.String())
+		}
+	}
+	var count int64
+	db.DB.Model(&models.Task{}).Count(&count)
+	if count != 2 {
+		t.Errorf("Ожидалось 2 задачи серии, найдено %d", count)
+	}
+	var done models.Task
+	db.DB.First(&done, task.ID)
+	if done.NextTaskID == nil || *done.NextTaskID != next.ID {
+		t.Errorf("Ожидалась ссылка на следующее вхождение %d, получено %v", next.ID, done.NextTaskID)
+	}
+}
+
+func TestRecurringTask_InvalidRule(t *testing.T) {
+	schemaName := db.InitTestDB()
+	defer db.DB.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schemaName))
+
+	req, _ := http.NewRequest("POST", "/tasks", strings.NewReader(`{"title":"Broken","rrule":"FREQ=SOMETIMES"}`))
+	req.Header.Set("UserID", "1")
+	rr := httptest.NewRecorder()
+
+	handlers.TasksHandler(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Ожидался статус %v, получен %v", http.StatusBadRequest, rr.Code)
+	}
+}
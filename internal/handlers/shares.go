@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"todo-api/internal/models"
+	"todo-api/pkg/db"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Обработчик для доступа к задаче: /tasks/{id}/shares[/{user_id}]
+func taskSharesHandler(w http.ResponseWriter, r *http.Request, id int, rest []string, userID int, role string) {
+	t, ok := findTask(id, userID, role)
+	if !ok {
+		http.Error(w, "Задача не найдена", http.StatusNotFound)
+		return
+	}
+	canManage := taskAccess(t, userID, role) >= accessManage
+
+	if len(rest) == 0 {
+		switch r.Method {
+		case "GET":
+			if !canManage {
+				http.Error(w, "Недостаточно прав", http.StatusForbidden)
+				return
+			}
+			shares := []models.TaskShare{}
+			if err := db.DB.Where("task_id = ?", id).Order("id").Find(&shares).Error; err != nil {
+				writeError(w, err, "Ошибка получения доступа к задаче")
+				return
+			}
+			json.NewEncoder(w).Encode(shares)
+		case "POST":
+			if !canManage {
+				http.Error(w, "Недостаточно прав", http.StatusForbidden)
+				return
+			}
+			var share models.TaskShare
+			if err := json.NewDecoder(r.Body).Decode(&share); err != nil {
+				http.Error(w, "Некорректный запрос", http.StatusBadRequest)
+				return
+			}
+			if err := validate.Struct(share); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if share.UserID == t.UserID {
+				http.Error(w, "Нельзя выдать доступ создателю задачи", http.StatusBadRequest)
+				return
+			}
+			share.ID = 0
+			share.TaskID = id
+			share.GrantedBy = userID
+
+			// Повторная выдача доступа меняет права
+			err := db.DB.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "task_id"}, {Name: "user_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"permission", "granted_by"}),
+			}).Create(&share).Error
+			if err != nil {
+				writeError(w, err, "Ошибка выдачи доступа к задаче")
+				return
+			}
+			InvalidateTaskLists()
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(share)
+		default:
+			http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	shareUserID, err := strconv.Atoi(rest[0])
+	if err != nil {
+		http.Error(w, "Некорректный ID пользователя", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case "DELETE":
+		// Отозвать доступ может управляющий задачей, отказаться — сам получатель
+		if !canManage && shareUserID != userID {
+			http.Error(w, "Недостаточно прав", http.StatusForbidden)
+			return
+		}
+		result := db.DB.Where("task_id = ? AND user_id = ?", id, shareUserID).Delete(&models.TaskShare{})
+		if result.Error != nil {
+			writeError(w, result.Error, "Ошибка отзыва доступа к задаче")
+			return
+		}
+		if result.RowsAffected == 0 {
+			http.Error(w, "Доступ не найден", http.StatusNotFound)
+			return
+		}
+		InvalidateTaskLists()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+	}
+}
+
+// sharedTaskIDs — подзапрос с задачами, к которым пользователю выдали доступ
+func sharedTaskIDs(userID int) *gorm.DB {
+	return db.DB.Model(&models.TaskShare{}).Select("task_id").Where("user_id = ?", userID)
+}
+
+// sharePermission возвращает права пользователя на задачу или пустую строку
+func sharePermission(tx *gorm.DB, taskID, userID int) (string, error) {
+	var share models.TaskShare
+	err := tx.Where("task_id = ? AND user_id = ?", taskID, userID).Limit(1).Find(&share).Error
+	return share.Permission, err
+}
@@ -0,0 +1,108 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"todo-api/internal/handlers"
+	"todo-api/internal/models"
+	"todo-api/pkg/db"
+)
+
+func TestTaskShares(t *testing.T) {
+	schemaName := initTestDB()
+	defer db.DB.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schemaName))
+
+	task := createTask(t, 1, `{"title":"Shared task"}`)
+	url := fmt.Sprintf("/tasks/%d", task.ID)
+
+	// Пользователь 2 получает чтение, пользователь 3 — редактирование
+	for _, share := range []string{`{"user_id":2,"permission":"read"}`, `{"user_id":3,"permission":"edit"}`} {
+		req, _ := http.NewRequest("POST", url+"/shares", strings.NewReader(share))
+		req.Header.Set("UserID", "1")
+		rr := httptest.NewRecorder()
+		handlers.TaskHandler(rr, req)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("Ожидался статус %v, получен %v: %s", http.StatusCreated, rr.Code, rr.Body.String())
+		}
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		userID     string
+		body       string
+		wantStatus int
+	}{
+		{"Чтение доступно", "GET", url, "2", "", http.StatusOK},
+		{"Чтение не даёт менять", "PATCH", url, "2", `{"title":"By reader"}`, http.StatusForbidden},
+		{"Редактирование даёт менять", "PATCH", url, "3", `{"title":"By editor"}`, http.StatusOK},
+		{"Редактирование не даёт удалять", "DELETE", url, "3", "", http.StatusForbidden},
+		{"Редактор не выдаёт доступ", "POST", url + "/shares", "3", `{"user_id":4,"permission":"read"}`, http.StatusForbidden},
+		{"Посторонний не видит", "GET", url, "4", "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/merge-patch+json")
+			req.Header.Set("UserID", tt.userID)
+			req.Header.Set("Role", models.RoleUser)
+			rr := httptest.NewRecorder()
+
+			handlers.TaskHandler(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("Ожидался статус %v, получен %v", tt.wantStatus, rr.Code)
+			}
+		})
+	}
+
+	// Общие задачи попадают в список только с include=shared
+	for query, want := range map[string]int{"": 0, "?include=shared": 1} {
+		req, _ := http.NewRequest("GET", "/tasks"+query, nil)
+		req.Header.Set("UserID", "2")
+		req.Header.Set("Role", models.RoleUser)
+		rr := httptest.NewRecorder()
+
+		handlers.TasksHandler(rr, req)
+
+		var tasks []models.Task
+		if err := json.NewDecoder(rr.Body).Decode(&tasks); err != nil {
+			t.Fatalf("Ошибка десериализации ответа: %v", err)
+		}
+		if len(tasks) != want {
+			t.Errorf("GET /tasks%s: ожидалось %d задач, получено %d", query, want, len(tasks))
+		}
+	}
+
+	// После отзыва доступа задача снова не видна
+	req, _ := http.NewRequest("DELETE", url+"/shares/2", nil)
+	req.Header.Set("UserID", "1")
+	rr := httptest.NewRecorder()
+	handlers.TaskHandler(rr, req)
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("Ожидался статус %v, получен %v", http.StatusNoContent, rr.Code)
+	}
+
+	req, _ = http.NewRequest("GET", url, nil)
+	req.Header.Set("UserID", "2")
+	rr = httptest.NewRecorder()
+	handlers.TaskHandler(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Ожидался статус %v, получен %v", http.StatusNotFound, rr.Code)
+	}
+
+	// Закэшированный список получателя тоже устаревает
+	req, _ = http.NewRequest("GET", "/tasks?include=shared", nil)
+	req.Header.Set("UserID", "2")
+	req.Header.Set("Role", models.RoleUser)
+	rr = httptest.NewRecorder()
+	handlers.TasksHandler(rr, req)
+	if got := rr.Header().Get("X-Total-Count"); got != "0" {
+		t.Errorf("После отзыва доступа ожидалось 0 задач в списке, получено %s", got)
+	}
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"todo-api/internal/models"
+	"todo-api/pkg/logger"
+)
+
+var (
+	transitionsOnce sync.Once
+	transitions     map[string][]string
+)
+
+// statusTransitions возвращает таблицу переходов между статусами. Её можно
+// задать переменной TASK_STATUS_TRANSITIONS в виде JSON-объекта
+// {"todo":["in_progress","done"],...}; некорректная таблица заменяется
+// таблицей по умолчанию.
+func statusTransitions() map[string][]string {
+	transitionsOnce.Do(func() {
+		transitions = models.DefaultStatusTransitions
+		config := os.Getenv("TASK_STATUS_TRANSITIONS")
+		if config == "" {
+			return
+		}
+		var custom map[string][]string
+		if err := json.Unmarshal([]byte(config), &custom); err != nil {
+			logger.Log.Errorf("Некорректная таблица TASK_STATUS_TRANSITIONS, используется таблица по умолчанию: %v", err)
+			return
+		}
+		for from, to := range custom {
+			for _, status := range append([]string{from}, to...) {
+				if !models.ValidTaskStatus(status) {
+					logger.Log.Errorf("Неизвестный статус %q в TASK_STATUS_TRANSITIONS, используется таблица по умолчанию", status)
+					return
+				}
+			}
+		}
+		transitions = custom
+	})
+	return transitions
+}
+
+// checkStatusTransition проверяет, что статус можно сменить с from на to
+func checkStatusTransition(from, to string) error {
+	if from == to {
+		return nil
+	}
+	allowed := statusTransitions()[from]
+	for _, status := range allowed {
+		if status == to {
+			return nil
+		}
+	}
+	if len(allowed) == 0 {
+		return newHTTPError(http.StatusUnprocessableEntity, "Недопустимый переход статуса %s → %s: из статуса %s переходы запрещены", from, to, from)
+	}
+	return newHTTPError(http.StatusUnprocessableEntity, "Недопустимый переход статуса %s → %s: из %s можно перейти в %s",
+		from, to, from, strings.Join(allowed, ", "))
+}
+
+// resolveTaskStatus согласует статус задачи с полем done. Клиенты, которые
+// знают только done, переводят задачу в done или todo; изменённый статус
+// важнее done. Для существующей задачи проверяется переход.
+func resolveTaskStatus(existing *models.Task, t *models.Task) error {
+	if t.Status == "" && existing != nil {
+		t.Status = existing.Status
+	}
+	doneChanged := existing == nil || t.Done != existing.Done
+	if t.Status == "" || (existing != nil && t.Status == existing.Status && doneChanged) {
+		t.Status = models.StatusTodo
+		if t.Done {
+			t.Status = models.StatusDone
+		}
+	}
+	if !models.ValidTaskStatus(t.Status) {
+		return newHTTPError(http.StatusBadRequest, "Неизвестный статус %q: ожидается одно из %s",
+			t.Status, strings.Join(models.TaskStatuses, ", "))
+	}
+	if existing == nil {
+		t.Done = t.Status == models.StatusDone
+		return nil
+	}
+	if t.Status != existing.Status {
+		t.Done = t.Status == models.StatusDone
+	}
+	return checkStatusTransition(existing.Status, t.Status)
+}
@@ -0,0 +1,103 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"todo-api/internal/handlers"
+	"todo-api/internal/models"
+	"todo-api/pkg/db"
+)
+
+func TestTaskHandler_StatusTransitions(t *testing.T) {
+	schemaName := db.InitTestDB()
+	defer db.DB.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schemaName))
+
+	task := createTask(t, 1, `{"title":"Write report"}`)
+	if task.Status != models.StatusTodo || task.Done {
+		t.Fatalf("Ожидался статус todo, получен %q, done=%t", task.Status, task.Done)
+	}
+
+	tests := []struct {
+		name       string
+		patch      string
+		wantStatus int
+		wantTask   string // Ожидаемый статус задачи после запроса
+		wantDone   bool
+	}{
+		{"todo → blocked", `{"status":"blocked"}`, http.StatusOK, models.StatusBlocked, false},
+		{"blocked → done запрещён", `{"status":"done"}`, http.StatusUnprocessableEntity, models.StatusBlocked, false},
+		{"done через поле done тоже проверяется", `{"done":true}`, http.StatusUnprocessableEntity, models.StatusBlocked, false},
+		{"Неизвестный статус", `{"status":"archived"}`, http.StatusBadRequest, models.StatusBlocked, false},
+		{"blocked → in_progress", `{"status":"in_progress"}`, http.StatusOK, models.StatusInProgress, false},
+		{"in_progress → review", `{"status":"review"}`, http.StatusOK, models.StatusReview, false},
+		{"done выводится из статуса", `{"status":"done"}`, http.StatusOK, models.StatusDone, true},
+		{"Снятие done возвращает в todo", `{"done":false}`, http.StatusOK, models.StatusTodo, false},
+		{"Установка done закрывает задачу", `{"done":true}`, http.StatusOK, models.StatusDone, true},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest("PATCH", fmt.Sprintf("/tasks/%d", task.ID), strings.NewReader(tt.patch))
+		req.Header.Set("UserID", "1")
+		req.Header.Set("Role", models.RoleUser)
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		rr := httptest.NewRecorder()
+		handlers.TaskHandler(rr, req)
+
+		if rr.Code != tt.wantStatus {
+			t.Errorf("%s: ожидался статус %v, получен %v: %s", tt.name, tt.wantStatus, rr.Code, rr.Body.String())
+		}
+		var saved models.Task
+		db.DB.First(&saved, task.ID)
+		if saved.Status != tt.wantTask || saved.Done != tt.wantDone {
+			t.Errorf("%s: ожидалось %s, done=%t, получено %s, done=%t", tt.name, tt.wantTask, tt.wantDone, saved.Status, saved.Done)
+		}
+	}
+}
+
+func TestTasksHandler_Get_StatusFilter(t *testing.T) {
+	schemaName := initTestDB()
+	defer db.DB.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schemaName))
+
+	createTask(t, 1, `{"title":"Todo task"}`)
+	createTask(t, 1, `{"title":"Blocked task","status":"blocked"}`)
+	createTask(t, 1, `{"title":"Review task","status":"review"}`)
+	done := createTask(t, 1, `{"title":"Done task","done":true}`)
+	if done.Status != models.StatusDone {
+		t.Errorf("Задача с done=true: ожидался статус done, получен %q", done.Status)
+	}
+
+	tests := []struct {
+		query     string
+		wantCode  int
+		wantCount int
+	}{
+		{"?status=blocked", http.StatusOK, 1},
+		{"?status=todo,review&status=done", http.StatusOK, 3},
+		{"?status=unknown", http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest("GET", "/tasks"+tt.query, nil)
+		req.Header.Set("UserID", "1")
+		req.Header.Set("Role", models.RoleUser)
+		rr := httptest.NewRecorder()
+		handlers.TasksHandler(rr, req)
+
+		if rr.Code != tt.wantCode {
+			t.Errorf("%s: ожидался статус %v, получен %v", tt.query, tt.wantCode, rr.Code)
+			continue
+		}
+		if rr.Code != http.StatusOK {
+			continue
+		}
+		var tasks []models.Task
+		if err := json.NewDecoder(rr.Body).Decode(&tasks); err != nil {
+			t.Fatalf("Ошибка десериализации: %v", err)
+		}
+		if len(tasks) != tt.wantCount {
+			t.Errorf("%s: ожидалось %d задач, получено %d", tt.query, tt.wantCount, len(tasks))
+		}
+	}
+}
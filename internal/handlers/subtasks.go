@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+	"todo-api/internal/models"
+	"todo-api/pkg/db"
+
+	"gorm.io/gorm"
+)
+
+// Максимальная глубина вложенности задач, включая корневую
+const maxTaskDepth = 5
+
+// Обработчик для подзадач: GET /tasks/{id}/subtasks
+func subtasksHandler(w http.ResponseWriter, r *http.Request, id, userID int, role string) {
+	if r.Method != "GET" {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, ok := findTask(id, userID, role); !ok {
+		http.Error(w, "Задача не найдена", http.StatusNotFound)
+		return
+	}
+
+	// Доступ к родителю не открывает чужие подзадачи: каждая проверяется отдельно
+	subtasks := []models.Task{}
+	query := taskScope(db.DB.Model(&models.Task{}), userID, role, true)
+	if err := query.Preload("Tags").Preload("Assignees").Where("parent_id = ?", id).Order("position, id").Find(&subtasks).Error; err != nil {
+		writeError(w, err, "Ошибка получения подзадач")
+		return
+	}
+	json.NewEncoder(w).Encode(subtasks)
+}
+
+// validateTaskParent проверяет, что родитель задачи существует, принадлежит
+// тому же пользователю или проекту, не создаёт цикл и не превышает глубину
+// вложенности
+func validateTaskParent(tx *gorm.DB, t models.Task) error {
+	if t.ParentID == nil {
+		return nil
+	}
+	if *t.ParentID == t.ID {
+		return newHTTPError(http.StatusBadRequest, "Задача не может быть подзадачей самой себя")
+	}
+
+	// Поднимаемся по цепочке родителей, считая глубину
+	depth := 1
+	for parentID := t.ParentID; parentID != nil; depth++ {
+		if t.ID != 0 && *parentID == t.ID {
+			return newHTTPError(http.StatusBadRequest, "Задача не может быть подзадачей своей подзадачи")
+		}
+		if depth > maxTaskDepth {
+			return newHTTPError(http.StatusBadRequest, "Превышена максимальная глубина вложенности (%d)", maxTaskDepth)
+		}
+		var parent models.Task
+		if err := tx.Select("id", "user_id", "project_id", "parent_id").First(&parent, *parentID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return newHTTPError(http.StatusBadRequest, "Родительская задача не найдена")
+			}
+			return err
+		}
+		// Подзадачи можно вешать на свои задачи или на задачи того же проекта
+		sameProject := parent.ProjectID != nil && t.ProjectID != nil && *parent.ProjectID == *t.ProjectID
+		if parent.UserID != t.UserID && !sameProject {
+			return newHTTPError(http.StatusBadRequest, "Родительская задача не найдена")
+		}
+		parentID = parent.ParentID
+	}
+
+	// Вместе с задачей переезжают и её подзадачи
+	height := 1
+	if t.ID != 0 {
+		var err error
+		if height, err = subtreeHeight(tx, t.ID); err != nil {
+			return err
+		}
+	}
+	if depth-1+height > maxTaskDepth {
+		return newHTTPError(http.StatusBadRequest, "Превышена максимальная глубина вложенности (%d)", maxTaskDepth)
+	}
+	return nil
+}
+
+// subtreeHeight возвращает число уровней в поддереве задачи, включая её саму
+func subtreeHeight(tx *gorm.DB, id int) (int, error) {
+	height := 1
+	ids := []int{id}
+	for len(ids) > 0 && height <= maxTaskDepth {
+		var children []int
+		if err := tx.Model(&models.Task{}).Where("parent_id IN ?", ids).Pluck("id", &children).Error; err != nil {
+			return 0, err
+		}
+		if len(children) == 0 {
+			break
+		}
+		ids = children
+		height++
+	}
+	return height, nil
+}
+
+// descendantIDs возвращает идентификаторы всех подзадач любого уровня
+func descendantIDs(tx *gorm.DB, id int) ([]int, error) {
+	var all []int
+	ids := []int{id}
+	for depth := 1; len(ids) > 0 && depth <= maxTaskDepth; depth++ {
+		var children []int
+		if err := tx.Model(&models.Task{}).Where("parent_id IN ?", ids).Pluck("id", &children).Error; err != nil {
+			return nil, err
+		}
+		all = append(all, children...)
+		ids = children
+	}
+	return all, nil
+}
+
+// completeParents закрывает родителей с auto_complete, у которых
+// после изменения задачи не осталось незавершённых подзадач. Родитель, из
+// статуса которого нельзя перейти в done, остаётся как есть.
+func completeParents(tx *gorm.DB, userID int, t models.Task) error {
+	for t.Done && t.ParentID != nil {
+		var parent models.Task
+		if err := tx.First(&parent, *t.ParentID).Error; err != nil {
+			return err
+		}
+		if parent.Done || !parent.AutoComplete {
+			return nil
+		}
+		var open int64
+		if err := tx.Model(&models.Task{}).Where("parent_id = ? AND done = ?", parent.ID, false).Count(&open).Error; err != nil {
+			return err
+		}
+		if open > 0 || checkStatusTransition(parent.Status, models.StatusDone) != nil {
+			return nil
+		}
+		if err := tx.Model(&parent).Updates(map[string]interface{}{"done": true, "status": models.StatusDone, "version": bumpVersion}).Error; err != nil {
+			return err
+		}
+		changes, err := fieldChange("done", false, true)
+		if err != nil {
+			return err
+		}
+		statusChanges, err := fieldChange("status", parent.Status, models.StatusDone)
+		if err != nil {
+			return err
+		}
+		for field, change := range statusChanges {
+			changes[field] = change
+		}
+		if err := recordTasksAction(tx, []int{parent.ID}, userID, models.HistoryUpdate, changes); err != nil {
+			return err
+		}
+		t = parent
+	}
+	return nil
+}
+
+// deleteTaskTree перемещает в корзину задачу вместе с подзадачами (cascade)
+// или переносит подзадачи к родителю удаляемой задачи (reparent).
+// Вложения остаются в хранилище до окончательной очистки корзины. Задача
+// удаляется, только если её версия всё ещё равна t.Version, иначе — 412,
+// как и при изменении через saveTaskVersion. Вся ветка получает одно время
+// удаления, по которому её потом восстанавливают.
+func deleteTaskTree(tx *gorm.DB, userID int, t models.Task, mode string) error {
+	deletedAt := time.Now()
+	result := tx.Model(&models.Task{}).Where("id = ? AND version = ?", t.ID, t.Version).UpdateColumn("deleted_at", deletedAt)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errVersionConflict()
+	}
+	var children int64
+	if err := tx.Model(&models.Task{}).Where("parent_id = ?", t.ID).Count(&children).Error; err != nil {
+		return err
+	}
+	var ids []int
+	if children > 0 {
+		switch mode {
+		case "cascade":
+			descendants, err := descendantIDs(tx, t.ID)
+			if err != nil {
+				return err
+			}
+			ids = descendants
+		case "reparent":
+			var childIDs []int
+			if err := tx.Model(&models.Task{}).Where("parent_id = ?", t.ID).Pluck("id", &childIDs).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.Task{}).Where("id IN ?", childIDs).Updates(map[string]interface{}{"parent_id": t.ParentID, "version": bumpVersion}).Error; err != nil {
+				return err
+			}
+			changes, err := fieldChange("parent_id", t.ID, t.ParentID)
+			if err != nil {
+				return err
+			}
+			if err := recordTasksAction(tx, childIDs, userID, models.HistoryUpdate, changes); err != nil {
+				return err
+			}
+		default:
+			return newHTTPError(http.StatusConflict, "У задачи есть подзадачи: укажите children=cascade или children=reparent")
+		}
+	}
+	if len(ids) > 0 {
+		if err := tx.Model(&models.Task{}).Where("id IN ?", ids).UpdateColumn("deleted_at", deletedAt).Error; err != nil {
+			return err
+		}
+	}
+	return recordTasksAction(tx, append([]int{t.ID}, ids...), userID, models.HistoryDelete, nil)
+}
+
+// restoreTaskTree возвращает задачу из корзины вместе с подзадачами,
+// удалёнными одновременно с ней
+func restoreTaskTree(tx *gorm.DB, userID int, t models.Task) error {
+	if t.ParentID != nil {
+		var parents int64
+		if err := tx.Model(&models.Task{}).Where("id = ?", *t.ParentID).Count(&parents).Error; err != nil {
+			return err
+		}
+		if parents == 0 {
+			return newHTTPError(http.StatusConflict, "Родительская задача в корзине: сначала восстановите её")
+		}
+	}
+	descendants, err := descendantIDs(tx.Unscoped().Session(&gorm.Session{}), t.ID)
+	if err != nil {
+		return err
+	}
+	var ids []int
+	err = tx.Unscoped().Model(&models.Task{}).
+		Where("id IN ? AND deleted_at = ?", append([]int{t.ID}, descendants...), t.DeletedAt).
+		Pluck("id", &ids).Error
+	if err != nil {
+		return err
+	}
+	if err := tx.Unscoped().Model(&models.Task{}).Where("id IN ?", ids).Update("deleted_at", nil).Error; err != nil {
+		return err
+	}
+	return recordTasksAction(tx, ids, userID, models.HistoryRestore, nil)
+}
+
+// attachSubtasks загружает доступные пользователю подзадачи всех уровней и
+// вкладывает их в задачи
+func attachSubtasks(tasks []models.Task, userID int, role string) error {
+	children := map[int][]models.Task{}
+	ids := make([]int, len(tasks))
+	for i, t := range tasks {
+		ids[i] = t.ID
+	}
+	for depth := 1; len(ids) > 0 && depth < maxTaskDepth; depth++ {
+		var level []models.Task
+		query := taskScope(db.DB.Model(&models.Task{}), userID, role, true)
+		if err := query.Preload("Tags").Preload("Assignees").Where("parent_id IN ?", ids).Order("position, id").Find(&level).Error; err != nil {
+			return err
+		}
+		ids = ids[:0]
+		for _, t := range level {
+			children[*t.ParentID] = append(children[*t.ParentID], t)
+			ids = append(ids, t.ID)
+		}
+	}
+
+	var attach func(t *models.Task)
+	attach = func(t *models.Task) {
+		t.Subtasks = children[t.ID]
+		for i := range t.Subtasks {
+			attach(&t.Subtasks[i])
+		}
+	}
+	for i := range tasks {
+		attach(&tasks[i])
+	}
+	return nil
+}
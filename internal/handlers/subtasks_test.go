@@ -0,0 +1,205 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"todo-api/internal/handlers"
+	"todo-api/internal/models"
+	"todo-api/pkg/db"
+)
+
+// createTask создаёт задачу через POST /tasks от имени пользователя
+func createTask(t *testing.T, userID int, body string) models.Task {
+	t.Helper()
+	req, _ := http.NewRequest("POST", "/tasks", strings.NewReader(body))
+	req.Header.Set("UserID", fmt.Sprintf("%d", userID))
+	req.Header.Set("Role", models.RoleUser)
+	rr := httptest.NewRecorder()
+
+	handlers.TasksHandler(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Ожидался статус %v, получен %v: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+	var task models.Task
+	if err := json.NewDecoder(rr.Body).Decode(&task); err != nil {
+		t.Fatalf("Ошибка десериализации ответа: %v", err)
+	}
+	return task
+}
+
+func TestSubtasks_AutoComplete(t *testing.T) {
+	schemaName := db.InitTestDB()
+	defer db.DB.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schemaName))
+
+	parent := createTask(t, 1, `{"title":"Release","auto_complete":true}`)
+	child1 := createTask(t, 1, fmt.Sprintf(`{"title":"Build","parent_id":%d}`, parent.ID))
+	child2 := createTask(t, 1, fmt.Sprintf(`{"title":"Deploy","parent_id":%d}`, parent.ID))
+
+	// Подзадачи доступны по отдельному адресу
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/tasks/%d/subtasks", parent.ID), nil)
+	req.Header.Set("UserID", "1")
+	rr := httptest.NewRecorder()
+	handlers.TaskHandler(rr, req)
+
+	var subtasks []models.Task
+	if err := json.NewDecoder(rr.Body).Decode(&subtasks); err != nil {
+		t.Fatalf("Ошибка десериализации ответа: %v", err)
+	}
+	if len(subtasks) != 2 {
+		t.Fatalf("Ожидалось 2 подзадачи, получено %d", len(subtasks))
+	}
+
+	for _, child := range []models.Task{child1, child2} {
+		req, _ := http.NewRequest("PATCH", fmt.Sprintf("/tasks/%d", child.ID), strings.NewReader(`{"done":true}`))
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		req.Header.Set("UserID", "1")
+		rr := httptest.NewRecorder()
+		handlers.TaskHandler(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Ожидался статус %v, получен %v: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+	}
+
+	var saved models.Task
+	db.DB.First(&saved, parent.ID)
+	if !saved.Done {
+		t.Error("Родитель должен закрыться, когда закрыты все подзадачи")
+	}
+}
+
+func TestSubtasks_AutoCompleteTransition(t *testing.T) {
+	schemaName := db.InitTestDB()
+	defer db.DB.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schemaName))
+
+	// Из blocked нельзя сразу перейти в done
+	parent := createTask(t, 1, `{"title":"Release","status":"blocked","auto_complete":true}`)
+	child := createTask(t, 1, fmt.Sprintf(`{"title":"Build","parent_id":%d}`, parent.ID))
+
+	req, _ := http.NewRequest("PATCH", fmt.Sprintf("/tasks/%d", child.ID), strings.NewReader(`{"done":true}`))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	req.Header.Set("UserID", "1")
+	rr := httptest.NewRecorder()
+	handlers.TaskHandler(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус %v, получен %v: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	var saved models.Task
+	db.DB.First(&saved, parent.ID)
+	if saved.Done || saved.Status != models.StatusBlocked {
+		t.Errorf("Родитель должен остаться в статусе %s, получен %s", models.StatusBlocked, saved.Status)
+	}
+}
+
+func TestSubtasks_ForeignParent(t *testing.T) {
+	schemaName := db.InitTestDB()
+	defer db.DB.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schemaName))
+
+	parent := createTask(t, 1, `{"title":"Owner task"}`)
+
+	req, _ := http.NewRequest("POST", "/tasks", strings.NewReader(fmt.Sprintf(`{"title":"Intruder","parent_id":%d}`, parent.ID)))
+	req.Header.Set("UserID", "2")
+	req.Header.Set("Role", models.RoleUser)
+	rr := httptest.NewRecorder()
+
+	handlers.TasksHandler(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Ожидался статус %v, получен %v", http.StatusBadRequest, rr.Code)
+	}
+}
+
+func TestSubtasks_DepthLimit(t *testing.T) {
+	schemaName := db.InitTestDB()
+	defer db.DB.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schemaName))
+
+	task := createTask(t, 1, `{"title":"Level 1"}`)
+	for level := 2; level <= 5; level++ {
+		task = createTask(t, 1, fmt.Sprintf(`{"title":"Level %d","parent_id":%d}`, level, task.ID))
+	}
+
+	req, _ := http.NewRequest("POST", "/tasks", strings.NewReader(fmt.Sprintf(`{"title":"Level 6","parent_id":%d}`, task.ID)))
+	req.Header.Set("UserID", "1")
+	rr := httptest.NewRecorder()
+
+	handlers.TasksHandler(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Ожидался статус %v, получен %v", http.StatusBadRequest, rr.Code)
+	}
+}
+
+func TestSubtasks_DeleteParent(t *testing.T) {
+	schemaName := db.InitTestDB()
+	defer db.DB.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schemaName))
+
+	parent := createTask(t, 1, `{"title":"Parent"}`)
+	child := createTask(t, 1, fmt.Sprintf(`{"title":"Child","parent_id":%d}`, parent.ID))
+
+	tests := []struct {
+		query      string
+		wantStatus int
+	}{
+		{"", http.StatusConflict},
+		{"?children=reparent", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest("DELETE", fmt.Sprintf("/tasks/%d%s", parent.ID, tt.query), nil)
+		req.Header.Set("UserID", "1")
+		rr := httptest.NewRecorder()
+
+		handlers.TaskHandler(rr, req)
+
+		if rr.Code != tt.wantStatus {
+			t.Errorf("DELETE%s: ожидался статус %v, получен %v", tt.query, tt.wantStatus, rr.Code)
+		}
+	}
+
+	var saved models.Task
+	if err := db.DB.First(&saved, child.ID).Error; err != nil {
+		t.Fatalf("Подзадача должна остаться после reparent: %v", err)
+	}
+	if saved.ParentID != nil {
+		t.Errorf("Ожидалась подзадача без родителя, получен parent_id %d", *saved.ParentID)
+	}
+}
+
+func TestSubtasks_SharedParent(t *testing.T) {
+	schemaName := initTestDB()
+	defer db.DB.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schemaName))
+
+	parent := createTask(t, 1, `{"title":"Release"}`)
+	createTask(t, 1, fmt.Sprintf(`{"title":"Private notes","parent_id":%d}`, parent.ID))
+
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/tasks/%d/shares", parent.ID), strings.NewReader(`{"user_id":2,"permission":"read"}`))
+	req.Header.Set("UserID", "1")
+	rr := httptest.NewRecorder()
+	handlers.TaskHandler(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Ожидался статус %v, получен %v: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+
+	// Доступ к родителю не открывает его подзадачи
+	for _, url := range []string{fmt.Sprintf("/tasks/%d/subtasks", parent.ID), "/tasks?include=shared&tree=true"} {
+		req, _ := http.NewRequest("GET", url, nil)
+		req.Header.Set("UserID", "2")
+		req.Header.Set("Role", models.RoleUser)
+		rr := httptest.NewRecorder()
+		if strings.HasPrefix(url, "/tasks/") {
+			handlers.TaskHandler(rr, req)
+		} else {
+			handlers.TasksHandler(rr, req)
+		}
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s: ожидался статус %v, получен %v", url, http.StatusOK, rr.Code)
+		}
+		if strings.Contains(rr.Body.String(), "Private notes") {
+			t.Errorf("%s: получатель доступа видит чужую подзадачу: %s", url, rr.Body.String())
+		}
+	}
+}
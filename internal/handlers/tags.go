@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"todo-api/internal/models"
+	"todo-api/pkg/db"
+	"todo-api/pkg/logger"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// tagUsage — метка и количество задач, в которых она используется
+type tagUsage struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	UserID int    `json:"user_id"`
+	Count  int    `json:"count"`
+}
+
+// Обработчик для списка меток
+func TagsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, _ := strconv.Atoi(r.Header.Get("UserID"))
+	role := r.Header.Get("Role")
+
+	switch r.Method {
+	case "GET":
+		query := db.DB.Table("tags").
+			Select("tags.id, tags.name, tags.user_id, COUNT(tasks.id) AS count").
+			Joins("LEFT JOIN task_tags ON task_tags.tag_id = tags.id").
+			// Задачи из корзины не учитываются
+			Joins("LEFT JOIN tasks ON tasks.id = task_tags.task_id AND tasks.deleted_at IS NULL").
+			Group("tags.id").
+			Order("tags.name, tags.id")
+		if role != models.RoleAdmin {
+			query = query.Where("tags.user_id = ?", userID)
+		}
+
+		tags := []tagUsage{}
+		if err := query.Scan(&tags).Error; err != nil {
+			logger.Log.Errorf("Ошибка получения меток: %v", err)
+			http.Error(w, "Ошибка получения меток", http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(tags)
+	default:
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+	}
+}
+
+// resolveTags находит метки пользователя по именам, создавая недостающие
+func resolveTags(tx *gorm.DB, userID int, tags []models.Tag) ([]models.Tag, error) {
+	var names []string
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		name := strings.TrimSpace(tag.Name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return []models.Tag{}, nil
+	}
+
+	newTags := make([]models.Tag, len(names))
+	for i, name := range names {
+		newTags[i] = models.Tag{Name: name, UserID: userID}
+	}
+	// Уже существующие метки пропускаем — уникальный индекс по (user_id, name)
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&newTags).Error; err != nil {
+		return nil, err
+	}
+
+	var resolved []models.Tag
+	if err := tx.Where("user_id = ? AND name IN ?", userID, names).Order("name").Find(&resolved).Error; err != nil {
+		return nil, err
+	}
+	return resolved, nil
+}
+
+// replaceTaskTags заменяет метки задачи. nil означает «метки не переданы»
+// и оставляет их без изменений, пустой список снимает все метки.
+func replaceTaskTags(tx *gorm.DB, t *models.Task, tags []models.Tag) error {
+	if tags == nil {
+		return nil
+	}
+	resolved, err := resolveTags(tx, t.UserID, tags)
+	if err != nil {
+		return err
+	}
+	association := tx.Model(t).Association("Tags")
+	if len(resolved) == 0 {
+		err = association.Clear()
+	} else {
+		err = association.Replace(resolved)
+	}
+	if err != nil {
+		return err
+	}
+	t.Tags = resolved
+	return nil
+}
@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"todo-api/internal/models"
+	"todo-api/pkg/db"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// keysetKey — один ключ порядка списка задач. Последовательность ключей
+// задаёт и ORDER BY, и условие продолжения выдачи после курсора.
+type keysetKey struct {
+	Column string // Колонка models.Task или rank для результатов поиска
+	Desc   bool
+}
+
+// taskCursor — содержимое непрозрачного курсора: значения ключей порядка у
+// последней выданной задачи и подпись сортировки, для которой он выдан
+type taskCursor struct {
+	Sort   string            `json:"s"`
+	Values []json.RawMessage `json:"v"`
+}
+
+// keysetKeys возвращает ключи порядка списка. Без явной сортировки
+// результаты поиска идут по релевантности, задачи одного проекта и задачи
+// вне проектов (project_id=none) — в ручном порядке, а остальные списки —
+// по id: в них смешаны проекты, у каждого из которых ручной порядок свой.
+// id в конце делает порядок однозначным.
+func (p taskListParams) keysetKeys() []keysetKey {
+	var keys []keysetKey
+	if len(p.Sort) == 0 {
+		if p.Q != "" {
+			keys = append(keys, keysetKey{Column: "rank", Desc: true})
+		} else if p.ProjectID != nil || p.NoProject {
+			keys = append(keys, keysetKey{Column: "position"})
+		}
+	}
+	hasID := false
+	for _, f := range p.Sort {
+		keys = append(keys, keysetKey{Column: f.Column, Desc: f.Desc})
+		hasID = hasID || f.Column == "id"
+	}
+	if !hasID {
+		keys = append(keys, keysetKey{Column: "id"})
+	}
+	return keys
+}
+
+// keyExpr возвращает SQL-выражение ключа порядка
+func (p taskListParams) keyExpr(column string) (string, []interface{}) {
+	if column == "rank" {
+		return "ts_rank(tasks.search_vector, websearch_to_tsquery(?, ?))", []interface{}{db.SearchConfig, p.Q}
+	}
+	return "tasks." + column, nil
+}
+
+// cursorSignature описывает порядок, к которому привязан курсор
+func (p taskListParams) cursorSignature() string {
+	return p.sortKey() + "|" + p.Q
+}
+
+// decodeCursor разбирает курсор в значения ключей порядка. Пустой курсор
+// означает первую страницу.
+func (p taskListParams) decodeCursor(s string) ([]interface{}, error) {
+	if s == "" {
+		return nil, nil
+	}
+	invalid := fmt.Errorf("Неверный параметр cursor")
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, invalid
+	}
+	var c taskCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, invalid
+	}
+	keys := p.keysetKeys()
+	if c.Sort != p.cursorSignature() || len(c.Values) != len(keys) {
+		return nil, fmt.Errorf("Курсор выдан для другой сортировки или поиска")
+	}
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		if values[i], err = decodeCursorValue(key.Column, c.Values[i]); err != nil {
+			return nil, invalid
+		}
+	}
+	return values, nil
+}
+
+// decodeCursorValue приводит значение из курсора к типу колонки
+func decodeCursorValue(column string, raw json.RawMessage) (interface{}, error) {
+	switch column {
+	case "rank", "position":
+		var v float64
+		return v, json.Unmarshal(raw, &v)
+	case "title":
+		var v string
+		return v, json.Unmarshal(raw, &v)
+	case "done":
+		var v bool
+		return v, json.Unmarshal(raw, &v)
+	case "due_date", "created_at", "updated_at":
+		var v *time.Time
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return nil, err
+		}
+		if v == nil {
+			if column != "due_date" {
+				return nil, fmt.Errorf("пустое значение %s", column)
+			}
+			// Задачи без срока идут после всех остальных, как NULL в ORDER BY
+			return clause.Expr{SQL: "'infinity'::timestamptz"}, nil
+		}
+		return *v, nil
+	default:
+		var v int
+		return v, json.Unmarshal(raw, &v)
+	}
+}
+
+// seek оставляет только задачи, идущие в порядке списка после курсора:
+// (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ...
+func (p taskListParams) seek(query *gorm.DB) *gorm.DB {
+	if len(p.After) == 0 {
+		return query
+	}
+	keys := p.keysetKeys()
+	exprs := make([]string, len(keys))
+	exprVars := make([][]interface{}, len(keys))
+	for i, key := range keys {
+		exprs[i], exprVars[i] = p.keyExpr(key.Column)
+		if key.Column == "due_date" {
+			exprs[i] = "COALESCE(" + exprs[i] + ", 'infinity'::timestamptz)"
+		}
+	}
+
+	var or []string
+	var vars []interface{}
+	for i, key := range keys {
+		var and []string
+		for j := 0; j < i; j++ {
+			and = append(and, exprs[j]+" = ?")
+			vars = append(append(vars, exprVars[j]...), p.After[j])
+		}
+		op := " > ?"
+		if key.Desc {
+			op = " < ?"
+		}
+		and = append(and, exprs[i]+op)
+		vars = append(append(vars, exprVars[i]...), p.After[i])
+		or = append(or, "("+strings.Join(and, " AND ")+")")
+	}
+	return query.Where(clause.Expr{SQL: "(" + strings.Join(or, " OR ") + ")", Vars: vars})
+}
+
+// nextCursor возвращает курсор следующей страницы или пустую строку, если
+// страница последняя или список запрошен без курсора
+func (p taskListParams) nextCursor(tasks []models.Task, hasMore bool) (string, error) {
+	if !p.UseCursor || !hasMore || len(tasks) == 0 {
+		return "", nil
+	}
+	last := tasks[len(tasks)-1]
+	c := taskCursor{Sort: p.cursorSignature()}
+	for _, key := range p.keysetKeys() {
+		value, err := json.Marshal(cursorValue(last, key.Column))
+		if err != nil {
+			return "", err
+		}
+		c.Values = append(c.Values, value)
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// cursorValue возвращает значение ключа порядка у задачи
+func cursorValue(t models.Task, column string) interface{} {
+	switch column {
+	case "rank":
+		return t.Rank
+	case "position":
+		return t.Position
+	case "title":
+		return t.Title
+	case "done":
+		return t.Done
+	case "due_date":
+		if t.DueDate == nil {
+			return nil
+		}
+		return t.DueDate.Time
+	case "created_at":
+		return t.CreatedAt
+	case "updated_at":
+		return t.UpdatedAt
+	default:
+		return t.ID
+	}
+}
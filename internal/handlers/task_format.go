@@ -0,0 +1,335 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"todo-api/internal/models"
+)
+
+// Форматы импорта и экспорта задач
+const (
+	formatCSV    = "csv"
+	formatJSON   = "json"
+	formatNDJSON = "ndjson"
+)
+
+// Типы содержимого форматов
+var taskFormatContentTypes = map[string]string{
+	formatCSV:    "text/csv; charset=utf-8",
+	formatJSON:   "application/json",
+	formatNDJSON: "application/x-ndjson",
+}
+
+// Колонки CSV в порядке выгрузки. При загрузке порядок берётся из заголовка.
+var taskCSVColumns = []string{
+	"id", "title", "done", "status", "due_date", "tags", "parent_id", "auto_complete",
+	"project_id", "rrule", "user_id", "created_at", "updated_at",
+}
+
+// Разделитель меток внутри ячейки CSV
+const csvTagSeparator = ";"
+
+// Максимальная длина строки NDJSON
+const maxNDJSONLine = 1 << 20
+
+// rowError — ошибка в отдельной записи, после которой чтение можно продолжить
+type rowError struct {
+	Err error
+}
+
+func (e *rowError) Error() string {
+	return e.Err.Error()
+}
+
+// taskWriter последовательно выгружает задачи в одном из форматов
+type taskWriter interface {
+	Write(t models.Task) error
+	Close() error
+}
+
+// taskReader последовательно читает задачи. По окончании возвращает io.EOF,
+// ошибка в одной записи возвращается как *rowError.
+type taskReader interface {
+	Next() (models.Task, error)
+}
+
+func newTaskWriter(format string, w io.Writer) taskWriter {
+	switch format {
+	case formatCSV:
+		return &csvTaskWriter{w: csv.NewWriter(w)}
+	case formatNDJSON:
+		return &ndjsonTaskWriter{w: w}
+	default:
+		return &jsonTaskWriter{w: w}
+	}
+}
+
+func newTaskReader(format string, r io.Reader) (taskReader, error) {
+	switch format {
+	case formatCSV:
+		return newCSVTaskReader(r)
+	case formatNDJSON:
+		s := bufio.NewScanner(r)
+		s.Buffer(make([]byte, 64*1024), maxNDJSONLine)
+		return &ndjsonTaskReader{s: s}, nil
+	default:
+		return newJSONTaskReader(r)
+	}
+}
+
+// jsonTaskWriter пишет JSON-массив, не собирая его в памяти
+type jsonTaskWriter struct {
+	w     io.Writer
+	count int
+}
+
+func (jw *jsonTaskWriter) Write(t models.Task) error {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	sep := ","
+	if jw.count == 0 {
+		sep = "["
+	}
+	jw.count++
+	if _, err := io.WriteString(jw.w, sep); err != nil {
+		return err
+	}
+	_, err = jw.w.Write(data)
+	return err
+}
+
+func (jw *jsonTaskWriter) Close() error {
+	end := "]\n"
+	if jw.count == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(jw.w, end)
+	return err
+}
+
+// ndjsonTaskWriter пишет по одной задаче в строке
+type ndjsonTaskWriter struct {
+	w io.Writer
+}
+
+func (nw *ndjsonTaskWriter) Write(t models.Task) error {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	_, err = nw.w.Write(append(data, '\n'))
+	return err
+}
+
+func (nw *ndjsonTaskWriter) Close() error {
+	return nil
+}
+
+// csvTaskWriter пишет заголовок и по строке на задачу
+type csvTaskWriter struct {
+	w      *csv.Writer
+	header bool
+}
+
+func (cw *csvTaskWriter) Write(t models.Task) error {
+	if !cw.header {
+		cw.header = true
+		if err := cw.w.Write(taskCSVColumns); err != nil {
+			return err
+		}
+	}
+	tags := make([]string, len(t.Tags))
+	for i, tag := range t.Tags {
+		tags[i] = tag.Name
+	}
+	dueDate := ""
+	if t.DueDate != nil {
+		data, _ := t.DueDate.MarshalJSON()
+		json.Unmarshal(data, &dueDate)
+	}
+	record := []string{
+		strconv.Itoa(t.ID),
+		t.Title,
+		strconv.FormatBool(t.Done),
+		t.Status,
+		dueDate,
+		strings.Join(tags, csvTagSeparator),
+		formatOptionalInt(t.ParentID),
+		strconv.FormatBool(t.AutoComplete),
+		formatOptionalInt(t.ProjectID),
+		t.RRule,
+		strconv.Itoa(t.UserID),
+		t.CreatedAt.UTC().Format(time.RFC3339Nano),
+		t.UpdatedAt.UTC().Format(time.RFC3339Nano),
+	}
+	if err := cw.w.Write(record); err != nil {
+		return err
+	}
+	// Сбрасываем буфер построчно, чтобы выгрузка шла потоком
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+func (cw *csvTaskWriter) Close() error {
+	if !cw.header {
+		cw.header = true
+		if err := cw.w.Write(taskCSVColumns); err != nil {
+			return err
+		}
+	}
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+func formatOptionalInt(v *int) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.Itoa(*v)
+}
+
+// jsonTaskReader читает элементы JSON-массива по одному
+type jsonTaskReader struct {
+	d *json.Decoder
+}
+
+func newJSONTaskReader(r io.Reader) (*jsonTaskReader, error) {
+	d := json.NewDecoder(r)
+	if token, err := d.Token(); err != nil || token != json.Delim('[') {
+		return nil, errors.New("ожидается JSON-массив задач")
+	}
+	return &jsonTaskReader{d: d}, nil
+}
+
+func (jr *jsonTaskReader) Next() (models.Task, error) {
+	var t models.Task
+	if !jr.d.More() {
+		if _, err := jr.d.Token(); err != nil {
+			return t, err
+		}
+		return t, io.EOF
+	}
+	var raw json.RawMessage
+	if err := jr.d.Decode(&raw); err != nil {
+		// Синтаксическая ошибка ломает весь поток, продолжить чтение нельзя
+		return t, err
+	}
+	if err := json.Unmarshal(raw, &t); err != nil {
+		return t, &rowError{Err: err}
+	}
+	return t, nil
+}
+
+// ndjsonTaskReader читает по одной задаче из строки, пропуская пустые
+type ndjsonTaskReader struct {
+	s *bufio.Scanner
+}
+
+func (nr *ndjsonTaskReader) Next() (models.Task, error) {
+	var t models.Task
+	for nr.s.Scan() {
+		line := bytes.TrimSpace(nr.s.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(line, &t); err != nil {
+			return t, &rowError{Err: err}
+		}
+		return t, nil
+	}
+	if err := nr.s.Err(); err != nil {
+		return t, err
+	}
+	return t, io.EOF
+}
+
+// csvTaskReader сопоставляет колонки по заголовку, неизвестные пропускает
+type csvTaskReader struct {
+	r       *csv.Reader
+	columns map[string]int
+}
+
+func newCSVTaskReader(r io.Reader) (*csvTaskReader, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, errors.New("ожидается CSV с заголовком")
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.TrimSpace(strings.ToLower(name))] = i
+	}
+	if _, ok := columns["title"]; !ok {
+		return nil, errors.New("в CSV нет колонки title")
+	}
+	return &csvTaskReader{r: cr, columns: columns}, nil
+}
+
+func (cr *csvTaskReader) Next() (models.Task, error) {
+	var t models.Task
+	record, err := cr.r.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return t, &rowError{Err: err}
+		}
+		return t, err
+	}
+	get := func(column string) string {
+		if i, ok := cr.columns[column]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	invalid := func(column string) (models.Task, error) {
+		return t, &rowError{Err: fmt.Errorf("некорректное значение %s: %q", column, get(column))}
+	}
+
+	t.Title = get("title")
+	t.Status = get("status")
+	t.RRule = get("rrule")
+	for column, target := range map[string]*bool{"done": &t.Done, "auto_complete": &t.AutoComplete} {
+		if s := get(column); s != "" {
+			v, err := strconv.ParseBool(s)
+			if err != nil {
+				return invalid(column)
+			}
+			*target = v
+		}
+	}
+	for column, target := range map[string]*int{"id": &t.ID, "user_id": &t.UserID} {
+		if s := get(column); s != "" {
+			v, err := strconv.Atoi(s)
+			if err != nil {
+				return invalid(column)
+			}
+			*target = v
+		}
+	}
+	if s := get("due_date"); s != "" {
+		d, err := models.ParseDueDate(s)
+		if err != nil {
+			return invalid("due_date")
+		}
+		t.DueDate = &d
+	}
+	if s := get("tags"); s != "" {
+		for _, name := range strings.Split(s, csvTagSeparator) {
+			if name = strings.TrimSpace(name); name != "" {
+				t.Tags = append(t.Tags, models.Tag{Name: name})
+			}
+		}
+	}
+	return t, nil
+}
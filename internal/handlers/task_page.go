@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"todo-api/internal/models"
+	"todo-api/pkg/db"
+	"todo-api/pkg/logger"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// Время жизни страниц и счётчиков списка задач в Redis
+	taskListCacheTTL = 10 * time.Minute
+	// Ключ Redis с поколением кэша списков. Оно входит в ключи страниц и
+	// счётчиков, поэтому после любого изменения задач старые записи
+	// перестают читаться и истекают сами.
+	taskListGenerationKey = "tasks:generation"
+)
+
+// taskListGeneration возвращает текущее поколение кэша списков. ok равен
+// false, если Redis недоступен — тогда кэш не читается и не пишется.
+func taskListGeneration(ctx context.Context) (gen int64, ok bool) {
+	gen, err := redisClient.Get(ctx, taskListGenerationKey).Int64()
+	if err != nil && err != redis.Nil {
+		logger.Log.Errorf("Ошибка чтения поколения кэша из Redis: %v", err)
+		return 0, false
+	}
+	return gen, true
+}
+
+// InvalidateTaskLists сбрасывает кэш списков задач и их счётчиков. Её
+// вызывают после каждого изменения задач, их видимости или порядка: задача
+// видна не только владельцу, поэтому сбрасываются списки всех пользователей.
+func InvalidateTaskLists() {
+	if err := redisClient.Incr(context.Background(), taskListGenerationKey).Err(); err != nil {
+		logger.Log.Errorf("Ошибка сброса кэша списков задач в Redis: %v", err)
+	}
+}
+
+// taskPage — страница списка задач в том виде, в котором она хранится в кэше
+type taskPage struct {
+	Tasks      json.RawMessage `json:"tasks"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+// taskListEnvelope — ответ GET /tasks?envelope=true
+type taskListEnvelope struct {
+	Items      json.RawMessage `json:"items"`
+	Page       int             `json:"page,omitempty"` // Не заполняется при выдаче по курсору
+	Limit      int             `json:"limit"`
+	Total      int64           `json:"total"`
+	HasNext    bool            `json:"has_next"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+// countTasks возвращает число задач, подходящих под фильтры списка.
+// Запрос строится без сортировки и позиции курсора. Без поколения кэша
+// (cached равен false) число всегда считается заново.
+func countTasks(ctx context.Context, p taskListParams, userID int, role string, gen int64, cached bool) (int64, error) {
+	key := p.countCacheKey(gen, userID, role)
+	if cached {
+		if total, err := redisClient.Get(ctx, key).Int64(); err == nil {
+			return total, nil
+		}
+	}
+	var total int64
+	if err := p.apply(taskScope(db.DB.Model(&models.Task{}), userID, role, p.IncludeShared)).Count(&total).Error; err != nil {
+		return 0, err
+	}
+	if !cached {
+		return total, nil
+	}
+	if err := redisClient.Set(ctx, key, total, taskListCacheTTL).Err(); err != nil {
+		logger.Log.Errorf("Ошибка записи в Redis: %v", err)
+	}
+	return total, nil
+}
+
+// write отдаёт страницу клиенту. Метаданные передаются в заголовках
+// X-Total-Count, Link и X-Next-Cursor, а с envelope=true — ещё и в теле.
+// ETag вычисляется по телу и общему числу задач, поэтому неизменившаяся
+// страница отдаётся как 304 Not Modified. Кэш читается только в текущем
+// поколении, так что после изменения задач ETag считается по свежим данным.
+func (page taskPage) write(w http.ResponseWriter, r *http.Request, p taskListParams, total int64) {
+	body := []byte(page.Tasks)
+	if p.Envelope {
+		body, _ = json.Marshal(page.envelope(p, total))
+	}
+	totalStr := strconv.FormatInt(total, 10)
+	w.Header().Set("X-Total-Count", totalStr)
+	w.Header().Set("Link", p.links(r.URL, total, page.NextCursor))
+	if page.NextCursor != "" {
+		w.Header().Set("X-Next-Cursor", page.NextCursor)
+	}
+	if notModified(w, r, payloadETag(body, []byte(totalStr))) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
+
+// envelope оборачивает страницу вместе с метаданными
+func (page taskPage) envelope(p taskListParams, total int64) taskListEnvelope {
+	envelope := taskListEnvelope{
+		Items:      page.Tasks,
+		Limit:      p.Limit,
+		Total:      total,
+		HasNext:    page.NextCursor != "",
+		NextCursor: page.NextCursor,
+	}
+	if !p.UseCursor {
+		envelope.Page = p.Page
+		envelope.HasNext = int64(p.Page*p.Limit) < total
+	}
+	return envelope
+}
+
+// links формирует заголовок Link (RFC 8288) со ссылками на соседние страницы
+func (p taskListParams) links(u *url.URL, total int64, nextCursor string) string {
+	link := func(rel, param, value string) string {
+		q := u.Query()
+		q.Set(param, value)
+		return fmt.Sprintf(`<%s?%s>; rel="%s"`, u.Path, q.Encode(), rel)
+	}
+
+	var links []string
+	if p.UseCursor {
+		links = append(links, link("first", "cursor", ""))
+		if nextCursor != "" {
+			links = append(links, link("next", "cursor", nextCursor))
+		}
+		return strings.Join(links, ", ")
+	}
+
+	last := int((total + int64(p.Limit) - 1) / int64(p.Limit))
+	if last < 1 {
+		last = 1
+	}
+	links = append(links, link("first", "page", "1"))
+	if p.Page > 1 {
+		links = append(links, link("prev", "page", strconv.Itoa(min(p.Page-1, last))))
+	}
+	if p.Page < last {
+		links = append(links, link("next", "page", strconv.Itoa(p.Page+1)))
+	}
+	links = append(links, link("last", "page", strconv.Itoa(last)))
+	return strings.Join(links, ", ")
+}
@@ -0,0 +1,417 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"todo-api/internal/models"
+	"todo-api/pkg/db"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// taskListParams — параметры выборки списка задач из строки запроса
+type taskListParams struct {
+	Page      int
+	Limit     int
+	Done      *bool
+	Statuses  []string
+	DueBefore *time.Time
+	DueAfter  *time.Time
+	Overdue   *bool
+	Sort      []sortField
+	Tags      []string
+	TagMatch  string
+	Tree      bool
+	ProjectID *int
+	// project_id=none — только задачи вне проектов
+	NoProject bool
+	// Задачи, назначенные пользователю, и задачи, созданные им
+	AssigneeID *int
+	CreatedBy  *int
+	// Добавить к своим задачам те, к которым выдали доступ
+	IncludeShared bool
+	// Полнотекстовый запрос и подсветка совпадений в ответе
+	Q         string
+	Highlight bool
+	// Постраничная выдача по курсору вместо номера страницы
+	UseCursor bool
+	Cursor    string
+	After     []interface{} // Значения ключей порядка из курсора
+	// Обернуть список в объект с метаданными постраничной выдачи
+	Envelope bool
+}
+
+// sortField — одно поле сортировки из параметра sort
+type sortField struct {
+	Column string
+	Desc   bool
+}
+
+// Колонки models.Task, по которым разрешена сортировка
+var taskSortColumns = map[string]bool{
+	"id":         true,
+	"title":      true,
+	"done":       true,
+	"due_date":   true,
+	"created_at": true,
+	"updated_at": true,
+	"position":   true,
+}
+
+// parseTaskListParams разбирает параметры GET /tasks. userID подставляется
+// вместо me в assignee и created_by.
+func parseTaskListParams(q url.Values, userID int) (taskListParams, error) {
+	var p taskListParams
+	var err error
+
+	p.Page, p.Limit = parsePagination(q)
+	if p.Done, err = parseBoolParam(q, "done"); err != nil {
+		return p, err
+	}
+	// status=todo,in_progress и status=todo&status=in_progress равнозначны
+	seenStatuses := map[string]bool{}
+	for _, status := range q["status"] {
+		for _, value := range strings.Split(status, ",") {
+			value = strings.TrimSpace(value)
+			if value == "" || seenStatuses[value] {
+				continue
+			}
+			if !models.ValidTaskStatus(value) {
+				return p, fmt.Errorf("Неверный параметр status: ожидается одно из %s", strings.Join(models.TaskStatuses, ", "))
+			}
+			seenStatuses[value] = true
+			p.Statuses = append(p.Statuses, value)
+		}
+	}
+	sort.Strings(p.Statuses)
+	if p.Overdue, err = parseBoolParam(q, "overdue"); err != nil {
+		return p, err
+	}
+	if p.DueBefore, err = parseDateParam(q, "due_before"); err != nil {
+		return p, err
+	}
+	if p.DueAfter, err = parseDateParam(q, "due_after"); err != nil {
+		return p, err
+	}
+	if p.Sort, err = parseSortParam(q.Get("sort")); err != nil {
+		return p, err
+	}
+	seenTags := map[string]bool{}
+	for _, tag := range q["tag"] {
+		if tag = strings.TrimSpace(tag); tag != "" && !seenTags[tag] {
+			seenTags[tag] = true
+			p.Tags = append(p.Tags, tag)
+		}
+	}
+	sort.Strings(p.Tags)
+	if s := q.Get("project_id"); s == "none" {
+		p.NoProject = true
+	} else if s != "" {
+		projectID, err := strconv.Atoi(s)
+		if err != nil {
+			return p, fmt.Errorf("Неверный параметр project_id: ожидается число или none")
+		}
+		p.ProjectID = &projectID
+	}
+	if p.AssigneeID, err = parseUserParam(q, "assignee", userID); err != nil {
+		return p, err
+	}
+	if p.CreatedBy, err = parseUserParam(q, "created_by", userID); err != nil {
+		return p, err
+	}
+	for _, include := range q["include"] {
+		for _, value := range strings.Split(include, ",") {
+			switch strings.TrimSpace(value) {
+			case "":
+			case "shared":
+				p.IncludeShared = true
+			default:
+				return p, fmt.Errorf("Неверный параметр include: %s не поддерживается", value)
+			}
+		}
+	}
+	p.Q = strings.TrimSpace(q.Get("q"))
+	highlight, err := parseBoolParam(q, "highlight")
+	if err != nil {
+		return p, err
+	}
+	p.Highlight = highlight != nil && *highlight
+	tree, err := parseBoolParam(q, "tree")
+	if err != nil {
+		return p, err
+	}
+	p.Tree = tree != nil && *tree
+	envelope, err := parseBoolParam(q, "envelope")
+	if err != nil {
+		return p, err
+	}
+	p.Envelope = envelope != nil && *envelope
+	// any — задача с любой из меток, all — со всеми сразу
+	p.TagMatch = q.Get("tag_match")
+	switch p.TagMatch {
+	case "":
+		p.TagMatch = "any"
+	case "any", "all":
+	default:
+		return p, fmt.Errorf("Неверный параметр tag_match: ожидается any или all")
+	}
+	// Пустой cursor запрашивает первую страницу в режиме курсоров
+	if q.Has("cursor") {
+		p.UseCursor = true
+		p.Cursor = q.Get("cursor")
+		if p.After, err = p.decodeCursor(p.Cursor); err != nil {
+			return p, err
+		}
+	}
+	return p, nil
+}
+
+// parseSortParam разбирает список полей вида "-created_at,title".
+// Минус перед полем означает сортировку по убыванию.
+func parseSortParam(s string) ([]sortField, error) {
+	var fields []sortField
+	seen := map[string]bool{}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		field := sortField{Column: strings.TrimPrefix(part, "-"), Desc: strings.HasPrefix(part, "-")}
+		if !taskSortColumns[field.Column] {
+			return nil, fmt.Errorf("Неверный параметр sort: поле %s не поддерживается", field.Column)
+		}
+		if seen[field.Column] {
+			continue
+		}
+		seen[field.Column] = true
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// parsePagination разбирает page и limit, общие для всех списков
+func parsePagination(q url.Values) (page, limit int) {
+	// Устанавливаем значения по умолчанию
+	page, err := strconv.Atoi(q.Get("page"))
+	if err != nil || page < 1 {
+		page = 1 // По умолчанию первая страница
+	}
+	limit, err = strconv.Atoi(q.Get("limit"))
+	if err != nil || limit < 1 {
+		limit = 10 // По умолчанию 10 записей
+	}
+	return page, limit
+}
+
+func parseBoolParam(q url.Values, name string) (*bool, error) {
+	s := q.Get(name)
+	if s == "" {
+		return nil, nil
+	}
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return nil, fmt.Errorf("Неверный параметр %s", name)
+	}
+	return &v, nil
+}
+
+// parseUserParam разбирает идентификатор пользователя или me
+func parseUserParam(q url.Values, name string, userID int) (*int, error) {
+	s := q.Get(name)
+	if s == "" {
+		return nil, nil
+	}
+	if s == "me" {
+		return &userID, nil
+	}
+	id, err := strconv.Atoi(s)
+	if err != nil {
+		return nil, fmt.Errorf("Неверный параметр %s: ожидается me или ID пользователя", name)
+	}
+	return &id, nil
+}
+
+func parseDateParam(q url.Values, name string) (*time.Time, error) {
+	s := q.Get(name)
+	if s == "" {
+		return nil, nil
+	}
+	d, err := models.ParseDueDate(s)
+	if err != nil {
+		return nil, fmt.Errorf("Неверный параметр %s: %v", name, err)
+	}
+	return &d.Time, nil
+}
+
+// Вычисляем смещение. При выдаче по курсору позицию задаёт seek.
+func (p taskListParams) offset() int {
+	if p.UseCursor {
+		return 0
+	}
+	return (p.Page - 1) * p.Limit
+}
+
+// cacheKey формирует ключ Redis для страницы списка задач в поколении gen.
+// Роль входит в ключ, потому что администратор видит чужие задачи.
+func (p taskListParams) cacheKey(gen int64, userID int, role string) string {
+	return fmt.Sprintf("tasks:%d:%s:page:%d:limit:%d:sort:%s:highlight:%t:cursor:%s",
+		gen, p.filterKey(userID, role), p.Page, p.Limit, p.sortKey(), p.Highlight, p.cursorKey())
+}
+
+// countCacheKey формирует ключ Redis для общего числа задач. Он зависит
+// только от фильтров, поэтому общий для всех страниц и сортировок.
+func (p taskListParams) countCacheKey(gen int64, userID int, role string) string {
+	return fmt.Sprintf("tasks:count:%d:%s", gen, p.filterKey(userID, role))
+}
+
+// filterKey — часть ключа кэша, которая описывает набор задач
+func (p taskListParams) filterKey(userID int, role string) string {
+	return fmt.Sprintf("user:%d:role:%s:done:%s:status:%s:due_before:%s:due_after:%s:overdue:%s:tags:%s:tag_match:%s:tree:%t:project:%s:assignee:%s:created_by:%s:shared:%t:q:%s",
+		userID, role, formatBoolKey(p.Done), strings.Join(p.Statuses, ","), formatTimeKey(p.DueBefore), formatTimeKey(p.DueAfter), formatBoolKey(p.Overdue),
+		strings.Join(p.Tags, ","), p.TagMatch, p.Tree, p.projectKey(), formatIntKey(p.AssigneeID), formatIntKey(p.CreatedBy), p.IncludeShared, p.Q)
+}
+
+// sortKey возвращает нормализованную запись сортировки
+func (p taskListParams) sortKey() string {
+	parts := make([]string, len(p.Sort))
+	for i, f := range p.Sort {
+		if f.Desc {
+			parts[i] = "-" + f.Column
+		} else {
+			parts[i] = f.Column
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// cursorKey отличает выдачу по курсору от выдачи по номеру страницы
+func (p taskListParams) cursorKey() string {
+	if !p.UseCursor {
+		return "nil"
+	}
+	return "=" + p.Cursor
+}
+
+// Формируем часть ключа с учётом nil и разыменования
+func formatBoolKey(v *bool) string {
+	if v == nil {
+		return "nil"
+	}
+	return strconv.FormatBool(*v)
+}
+
+// projectKey — часть ключа кэша для фильтра project_id
+func (p taskListParams) projectKey() string {
+	if p.NoProject {
+		return "none"
+	}
+	return formatIntKey(p.ProjectID)
+}
+
+func formatIntKey(v *int) string {
+	if v == nil {
+		return "nil"
+	}
+	return strconv.Itoa(*v)
+}
+
+func formatTimeKey(v *time.Time) string {
+	if v == nil {
+		return "nil"
+	}
+	return v.UTC().Format(time.RFC3339)
+}
+
+// order добавляет к запросу сортировку списка по ключам keysetKeys
+func (p taskListParams) order(query *gorm.DB) *gorm.DB {
+	var columns []string
+	var vars []interface{}
+	for _, key := range p.keysetKeys() {
+		expr, exprVars := p.keyExpr(key.Column)
+		if key.Desc {
+			expr += " DESC"
+		}
+		columns = append(columns, expr)
+		vars = append(vars, exprVars...)
+	}
+	return query.Clauses(clause.OrderBy{Expression: clause.Expr{
+		SQL:                strings.Join(columns, ", "),
+		Vars:               vars,
+		WithoutParentheses: true,
+	}})
+}
+
+// selectColumns добавляет к выборке релевантность и подсветку при поиске
+func (p taskListParams) selectColumns(query *gorm.DB) *gorm.DB {
+	if p.Q == "" {
+		return query
+	}
+	if p.Highlight {
+		// Заголовок экранируется до подсветки, чтобы в ответ не попала чужая разметка
+		return query.Select(`tasks.*,
+			ts_rank(tasks.search_vector, websearch_to_tsquery(?, ?)) AS rank,
+			ts_headline(?, replace(replace(replace(tasks.title, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'),
+				websearch_to_tsquery(?, ?), 'StartSel=<mark>, StopSel=</mark>, HighlightAll=true') AS highlight`,
+			db.SearchConfig, p.Q, db.SearchConfig, db.SearchConfig, p.Q)
+	}
+	return query.Select("tasks.*, ts_rank(tasks.search_vector, websearch_to_tsquery(?, ?)) AS rank", db.SearchConfig, p.Q)
+}
+
+// apply добавляет к запросу фильтры списка
+func (p taskListParams) apply(query *gorm.DB) *gorm.DB {
+	if p.Tree {
+		// В режиме дерева постранично выдаются только корневые задачи
+		query = query.Where("parent_id IS NULL")
+	}
+	if p.ProjectID != nil {
+		query = query.Where("tasks.project_id = ?", *p.ProjectID)
+	}
+	if p.NoProject {
+		query = query.Where("tasks.project_id IS NULL")
+	}
+	if p.AssigneeID != nil {
+		query = query.Where("tasks.id IN (?)", assignedTaskIDs(*p.AssigneeID))
+	}
+	if p.CreatedBy != nil {
+		query = query.Where("tasks.user_id = ?", *p.CreatedBy)
+	}
+	if p.Q != "" {
+		query = query.Where("tasks.search_vector @@ websearch_to_tsquery(?, ?)", db.SearchConfig, p.Q)
+	}
+	if p.Done != nil {
+		query = query.Where("done = ?", *p.Done)
+	}
+	if len(p.Statuses) > 0 {
+		query = query.Where("tasks.status IN ?", p.Statuses)
+	}
+	if p.DueBefore != nil {
+		query = query.Where("due_date < ?", *p.DueBefore)
+	}
+	if p.DueAfter != nil {
+		query = query.Where("due_date >= ?", *p.DueAfter)
+	}
+	if p.Overdue != nil {
+		now := time.Now()
+		if *p.Overdue {
+			query = query.Where("done = ? AND due_date < ?", false, now)
+		} else {
+			query = query.Where("done = ? OR due_date IS NULL OR due_date >= ?", true, now)
+		}
+	}
+	if len(p.Tags) > 0 {
+		tagged := db.DB.Table("task_tags").
+			Select("task_tags.task_id").
+			Joins("JOIN tags ON tags.id = task_tags.tag_id").
+			Where("tags.name IN ?", p.Tags)
+		if p.TagMatch == "all" {
+			tagged = tagged.Group("task_tags.task_id").Having("COUNT(DISTINCT tags.name) = ?", len(p.Tags))
+		}
+		query = query.Where("tasks.id IN (?)", tagged)
+	}
+	return query
+}
@@ -3,16 +3,23 @@ package handlers
 import (
 	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
+	"io"
+	"mime"
 	"net/http"
+	"slices"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 	"todo-api/internal/models"
 	"todo-api/pkg/db"
 	"todo-api/pkg/logger"
+	"todo-api/pkg/mergepatch"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
 )
 
 var validate = validator.New()
@@ -27,87 +34,92 @@ func TasksHandler(w http.ResponseWriter, r *http.Request) {
 	userID, _ := strconv.Atoi(userIDStr)
 	switch r.Method {
 	case "GET":
-		pageStr := r.URL.Query().Get("page")
-		limitStr := r.URL.Query().Get("limit")
-		doneStr := r.URL.Query().Get("done")
-
-		// Устанавливаем значения по умолчанию
-		page, err := strconv.Atoi(pageStr)
-		if err != nil || page < 1 {
-			page = 1 // По умолчанию первая страница
-		}
-		limit, err := strconv.Atoi(limitStr)
-		if err != nil || limit < 1 {
-			limit = 10 // По умолчанию 10 записей
-		}
-
-		// Преобразуем done в bool (если параметр передан)
-		var doneFilter *bool
-		if doneStr != "" {
-			done, err := strconv.ParseBool(doneStr)
-			if err != nil {
-				logger.Log.Warnf("Неверный параметр done: %v", err)
-				http.Error(w, "Неверный параметр done", http.StatusBadRequest)
-				return
-			}
-			doneFilter = &done
+		params, err := parseTaskListParams(r.URL.Query(), userID)
+		if err != nil {
+			logger.Log.Warnf("Неверные параметры списка задач: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
 		}
 
-		// Вычисляем смещение
-		offset := (page - 1) * limit
+		// Ключ для кэша. Поколение читается до запросов к базе: страница,
+		// собранная одновременно с изменением задач, попадёт в старое
+		// поколение и больше не будет прочитана.
+		ctx := context.Background()
+		gen, cacheOK := taskListGeneration(ctx)
+		cacheKey := params.cacheKey(gen, userID, role)
 
-		// Формируем ключ с учётом nil и разыменования
-		var doneValue string
-		if doneFilter != nil {
-			doneValue = strconv.FormatBool(*doneFilter)
-		} else {
-			doneValue = "nil"
+		// Общее число задач кэшируется отдельно от страниц
+		total, err := countTasks(ctx, params, userID, role, gen, cacheOK)
+		if err != nil {
+			logger.Log.Errorf("Ошибка подсчёта задач: %v", err)
+			http.Error(w, "Ошибка получения задач", http.StatusInternalServerError)
+			return
 		}
-		// Ключ для кэша
-		cacheKey := fmt.Sprintf("tasks:user:%d:page:%d:limit:%d:done:%s", userID, page, limit, doneValue)
-		ctx := context.Background()
 
 		// Проверяем кэш
+		var page taskPage
 		cached, err := redisClient.Get(ctx, cacheKey).Bytes()
-		if err == nil {
+		if cacheOK && err == nil && json.Unmarshal(cached, &page) == nil {
 			logger.Log.Info("Данные взяты из кэша")
-			w.Header().Set("Content-Type", "application/json")
-			w.Write(cached)
+			page.write(w, r, params, total)
 			return
 		} else {
 			logger.Log.Info("Данные не взяты из кэша", err)
 		}
 
 		// Формируем запрос
-		query := db.DB.Model(&models.Task{})
-		if role != models.RoleAdmin {
-			query = query.Where("user_id = ?", userID)
-		}
-		if doneFilter != nil {
-			query = query.Where("done = ?", *doneFilter)
-		}
+		query := params.selectColumns(params.order(params.seek(params.apply(taskScope(db.DB.Model(&models.Task{}), userID, role, params.IncludeShared)))))
 
-		// Применяем пагинацию и получаем задачи
+		// Применяем пагинацию и получаем задачи. В режиме курсоров берём
+		// одну лишнюю задачу, чтобы узнать, есть ли следующая страница.
+		limit := params.Limit
+		if params.UseCursor {
+			limit++
+		}
 		var tasks []models.Task
-		if err := query.Offset(offset).Limit(limit).Find(&tasks).Error; err != nil {
+		if err := query.Preload("Tags").Preload("Assignees").Offset(params.offset()).Limit(limit).Find(&tasks).Error; err != nil {
 			logger.Log.Errorf("Ошибка получения задач: %v", err)
 			http.Error(w, "Ошибка получения задач", http.StatusInternalServerError)
 			return
 		}
+		hasMore := len(tasks) > params.Limit
+		if hasMore {
+			tasks = tasks[:params.Limit]
+		}
+		if params.Tree {
+			if err := attachSubtasks(tasks, userID, role); err != nil {
+				logger.Log.Errorf("Ошибка получения подзадач: %v", err)
+				http.Error(w, "Ошибка получения задач", http.StatusInternalServerError)
+				return
+			}
+		}
 
 		// Сериализуем и кэшируем
-		jsonData, _ := json.Marshal(tasks)
-		if err := redisClient.Set(ctx, cacheKey, jsonData, 10*time.Minute).Err(); err != nil {
-			logger.Log.Errorf("Ошибка записи в Redis: %v", err)
-			// Не прерываем выполнение, так как это не критично
-		} else {
-			logger.Log.Info("Данные сохранены в кэш")
+		if page.NextCursor, err = params.nextCursor(tasks, hasMore); err != nil {
+			logger.Log.Errorf("Ошибка формирования курсора: %v", err)
+			http.Error(w, "Ошибка получения задач", http.StatusInternalServerError)
+			return
+		}
+		page.Tasks, _ = json.Marshal(tasks)
+		jsonData, _ := json.Marshal(page)
+		if cacheOK {
+			if err := redisClient.Set(ctx, cacheKey, jsonData, taskListCacheTTL).Err(); err != nil {
+				logger.Log.Errorf("Ошибка записи в Redis: %v", err)
+				// Не прерываем выполнение, так как это не критично
+			} else {
+				logger.Log.Info("Данные сохранены в кэш")
+			}
 		}
 
-		json.NewEncoder(w).Encode(tasks)
+		page.write(w, r, params, total)
 	case "POST":
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "Некорректный запрос", http.StatusBadRequest)
+			return
+		}
 		var t models.Task
-		if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+		if err := json.Unmarshal(body, &t); err != nil {
 			http.Error(w, "Некорректный запрос", http.StatusBadRequest)
 			return
 		}
@@ -119,93 +131,534 @@ func TasksHandler(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "UserID не найден в заголовке", http.StatusBadRequest)
 			return
 		}
-		t.UserID = userID
 
-		if err := validate.Struct(t); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+		// Повтор запроса с тем же Idempotency-Key не создаёт новую задачу
+		idem, handled := startIdempotentRequest(r.Context(), w, r, userID, body)
+		if handled {
 			return
 		}
 
 		// Сохраняем задачу в базе данных
-		if err := db.DB.Create(&t).Error; err != nil {
-			http.Error(w, "Ошибка создания задачи", http.StatusInternalServerError)
+		err = db.DB.Transaction(func(tx *gorm.DB) error {
+			return insertTask(tx, &t, userID, userID, role)
+		})
+		if err != nil {
+			idem.release(r.Context())
+			writeError(w, err, "Ошибка создания задачи")
 			return
 		}
+		InvalidateTaskLists()
+		data, _ := json.Marshal(t)
+		data = append(data, '\n')
+		idem.complete(r.Context(), http.StatusCreated, taskETag(t), data)
 
 		ch := make(chan string, 1) // Буферизированный канал
 		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 		defer cancel()
-		go notifyUser(ctx, userID, t.Title, ch)
+		go notifyUser(ctx, userID, t.Title, "создана", ch)
+		notifyAssignees(t, userID, addedAssignees(nil, t))
 
 		// Можно не ждать результата в реальном коде, но для примера:
 		notificationResult := <-ch
 		logger.Log.Infof("Результат уведомления: %s", notificationResult)
 
+		w.Header().Set("ETag", taskETag(t))
 		w.WriteHeader(http.StatusCreated)
-		json.NewEncoder(w).Encode(t)
+		w.Write(data)
 
 	default:
 		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
 	}
 }
 
-// Обработчик для конкретной задачи
+// Обработчик для конкретной задачи и её вложенных ресурсов
 func TaskHandler(w http.ResponseWriter, r *http.Request) {
-	idStr := r.URL.Path[len("/tasks/"):]
-	id, err := strconv.Atoi(idStr)
+	parts := strings.Split(strings.Trim(r.URL.Path[len("/tasks/"):], "/"), "/")
+	userID, _ := strconv.Atoi(r.Header.Get("UserID"))
+	role := r.Header.Get("Role")
+
+	if len(parts) == 1 {
+		switch parts[0] {
+		case "trash":
+			trashHandler(w, r, userID, role)
+			return
+		case "export":
+			exportTasksHandler(w, r, userID, role)
+			return
+		case "import":
+			importTasksHandler(w, r, userID, role)
+			return
+		}
+	}
+	id, err := strconv.Atoi(parts[0])
 	if err != nil {
 		http.Error(w, "Некорректный ID", http.StatusBadRequest)
 		return
 	}
 
+	if len(parts) > 1 {
+		switch parts[1] {
+		case "subtasks":
+			subtasksHandler(w, r, id, userID, role)
+		case "shares":
+			taskSharesHandler(w, r, id, parts[2:], userID, role)
+		case "comments":
+			taskCommentsHandler(w, r, id, parts[2:], userID, role)
+		case "attachments":
+			taskAttachmentsHandler(w, r, id, parts[2:], userID, role)
+		case "occurrences":
+			taskOccurrencesHandler(w, r, id, userID, role)
+		case "history":
+			taskHistoryHandler(w, r, id, userID, role)
+		case "restore":
+			restoreTaskHandler(w, r, id, userID, role)
+		case "move":
+			moveTaskHandler(w, r, id, userID, role)
+		default:
+			http.NotFound(w, r)
+		}
+		return
+	}
+
 	switch r.Method {
 	case "GET":
-		var t models.Task
-		if err := db.DB.First(&t, id).Error; err != nil {
+		t, ok := findTask(id, userID, role)
+		if !ok {
 			http.Error(w, "Задача не найдена", http.StatusNotFound)
 			return
 		}
+		if notModified(w, r, taskETag(t)) {
+			return
+		}
 		json.NewEncoder(w).Encode(t)
 	case "PUT":
-		var t models.Task
-		if !taskExists(t) {
+		existing, ok := findTask(id, userID, role)
+		if !ok {
 			w.WriteHeader(http.StatusNotFound)
 			json.NewEncoder(w).Encode(map[string]string{"message": "Задача не найдена"})
 			return
 		}
+		if taskAccess(existing, userID, role) < accessEdit {
+			http.Error(w, "Недостаточно прав", http.StatusForbidden)
+			return
+		}
+		if err := checkIfMatch(r, existing); err != nil {
+			writeError(w, err, "Ошибка обновления задачи")
+			return
+		}
+		var t models.Task
 		if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
 			http.Error(w, "Некорректный запрос", http.StatusBadRequest)
 			return
 		}
+		if err := resolveTaskStatus(&existing, &t); err != nil {
+			writeError(w, err, "Ошибка обновления задачи")
+			return
+		}
 		if err := validate.Struct(t); err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		// Владельца задачи менять нельзя, даже администратору
 		t.ID = id
-		if err := db.DB.Save(&t).Error; err != nil {
-			http.Error(w, "Ошибка обновления задачи", http.StatusInternalServerError)
+		t.UserID = existing.UserID
+		t.CreatedAt = existing.CreatedAt
+		t.Position = existing.Position // Порядок меняется только через /move
+		t.NextTaskID = existing.NextTaskID
+		tags, assignees := t.Tags, t.Assignees
+		if err := checkAssigneeChange(existing, assignees, userID, role); err != nil {
+			writeError(w, err, "Ошибка обновления задачи")
 			return
 		}
+		err := db.DB.Transaction(func(tx *gorm.DB) error {
+			if err := validateTaskProject(tx, t, existing.ProjectID, userID, role); err != nil {
+				return err
+			}
+			if err := validateTaskParent(tx, t); err != nil {
+				return err
+			}
+			// В другом проекте задача встаёт в конец его порядка
+			if !sameTaskList(existing, t) {
+				var err error
+				if t.Position, err = nextTaskPosition(tx, t); err != nil {
+					return err
+				}
+			}
+			if err := saveTaskVersion(tx, &t, existing.Version); err != nil {
+				return err
+			}
+			if err := replaceTaskTags(tx, &t, tags); err != nil {
+				return err
+			}
+			if err := replaceTaskAssignees(tx, &t, assignees, userID); err != nil {
+				return err
+			}
+			return afterTaskUpdate(tx, userID, existing, &t)
+		})
+		if err != nil {
+			writeError(w, err, "Ошибка обновления задачи")
+			return
+		}
+		InvalidateTaskLists()
+		if t.Tags == nil {
+			t.Tags = existing.Tags
+		}
+		if t.Assignees == nil {
+			t.Assignees = existing.Assignees
+		}
+		notifyAssignees(t, userID, addedAssignees(&existing, t))
+		w.Header().Set("ETag", taskETag(t))
 		json.NewEncoder(w).Encode(t)
-	case "DELETE":
+	case "PATCH":
+		existing, ok := findTask(id, userID, role)
+		if !ok {
+			http.Error(w, "Задача не найдена", http.StatusNotFound)
+			return
+		}
+		if taskAccess(existing, userID, role) < accessEdit {
+			http.Error(w, "Недостаточно прав", http.StatusForbidden)
+			return
+		}
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/merge-patch+json" && mediaType != "application/json" {
+			http.Error(w, "Ожидается application/merge-patch+json", http.StatusUnsupportedMediaType)
+			return
+		}
+		if err := checkIfMatch(r, existing); err != nil {
+			writeError(w, err, "Ошибка обновления задачи")
+			return
+		}
+		patch, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "Некорректный запрос", http.StatusBadRequest)
+			return
+		}
 		var t models.Task
-		if !taskExists(t) {
-			http.Error(w, "Задача не найдена", http.StatusBadRequest)
+		err = db.DB.Transaction(func(tx *gorm.DB) error {
+			var err error
+			t, err = patchTask(tx, existing, patch, userID, role)
+			return err
+		})
+		if err != nil {
+			writeError(w, err, "Ошибка обновления задачи")
+			return
+		}
+		InvalidateTaskLists()
+		notifyAssignees(t, userID, addedAssignees(&existing, t))
+		w.Header().Set("ETag", taskETag(t))
+		json.NewEncoder(w).Encode(t)
+	case "DELETE":
+		t, ok := findTask(id, userID, role)
+		if !ok {
+			http.Error(w, "Задача не найдена", http.StatusNotFound)
+			return
+		}
+		if taskAccess(t, userID, role) < accessManage {
+			http.Error(w, "Недостаточно прав", http.StatusForbidden)
+			return
+		}
+		if err := checkIfMatch(r, t); err != nil {
+			writeError(w, err, "Ошибка удаления задачи")
 			return
 		}
-		if err := db.DB.Delete(&models.Task{}, id).Error; err != nil {
-			http.Error(w, "Ошибка удаления задачи", http.StatusInternalServerError)
+		// children=cascade|reparent определяет судьбу подзадач
+		mode := r.URL.Query().Get("children")
+		err := db.DB.Transaction(func(tx *gorm.DB) error {
+			return deleteTaskTree(tx, userID, t, mode)
+		})
+		if err != nil {
+			writeError(w, err, "Ошибка удаления задачи")
 			return
 		}
+		InvalidateTaskLists()
 		w.WriteHeader(http.StatusNoContent)
 	default:
 		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
 	}
 }
 
-func taskExists(task models.Task) bool {
-	if err := db.DB.First(&task).Error; err != nil {
-		return false
+// taskScope ограничивает запрос задачами, доступными пользователю:
+// администратор видит все задачи, остальные — свои, назначенные им и задачи
+// своих проектов, а с includeShared ещё и задачи, к которым им выдали доступ
+func taskScope(query *gorm.DB, userID int, role string, includeShared bool) *gorm.DB {
+	if role == models.RoleAdmin {
+		return query
+	}
+	if includeShared {
+		return query.Where("tasks.user_id = ? OR tasks.project_id IN (?) OR tasks.id IN (?) OR tasks.id IN (?)",
+			userID, memberProjectIDs(userID), assignedTaskIDs(userID), sharedTaskIDs(userID))
+	}
+	return query.Where("tasks.user_id = ? OR tasks.project_id IN (?) OR tasks.id IN (?)",
+		userID, memberProjectIDs(userID), assignedTaskIDs(userID))
+}
+
+// Уровни доступа пользователя к задаче
+const (
+	accessNone   = iota
+	accessRead   // Просмотр
+	accessEdit   // Изменение полей задачи
+	accessManage // Удаление задачи и выдача доступа другим
+)
+
+// taskAccess возвращает уровень доступа пользователя к задаче. Полный
+// доступ у администратора, создателя задачи и редакторов и владельцев её
+// проекта; исполнитель может изменять задачу, остальные получают права через
+// роль наблюдателя или выданный доступ.
+func taskAccess(t models.Task, userID int, role string) int {
+	if role == models.RoleAdmin || t.UserID == userID {
+		return accessManage
+	}
+	access := accessNone
+	if t.ProjectID != nil {
+		projectRole, err := projectRole(db.DB, *t.ProjectID, userID)
+		if err != nil {
+			logger.Log.Errorf("Ошибка проверки роли в проекте %d: %v", *t.ProjectID, err)
+			return accessNone
+		}
+		switch projectRole {
+		case models.ProjectRoleEditor, models.ProjectRoleOwner:
+			return accessManage
+		case models.ProjectRoleViewer:
+			access = accessRead
+		}
+	}
+	assigned, err := isTaskAssignee(db.DB, t.ID, userID)
+	if err != nil {
+		logger.Log.Errorf("Ошибка проверки исполнителей задачи %d: %v", t.ID, err)
+		return access
+	}
+	if assigned {
+		access = accessEdit
+	}
+	permission, err := sharePermission(db.DB, t.ID, userID)
+	if err != nil {
+		logger.Log.Errorf("Ошибка проверки доступа к задаче %d: %v", t.ID, err)
+		return access
+	}
+	switch permission {
+	case models.SharePermissionEdit:
+		access = max(access, accessEdit)
+	case models.SharePermissionRead:
+		access = max(access, accessRead)
+	}
+	return access
+}
+
+// validateTaskProject проверяет, что пользователь может добавлять задачи
+// в проект. previous — проект задачи до изменения: оставить задачу в её
+// прежнем проекте можно без проверки.
+func validateTaskProject(tx *gorm.DB, t models.Task, previous *int, userID int, role string) error {
+	if t.ProjectID == nil || (previous != nil && *previous == *t.ProjectID) {
+		return nil
+	}
+	if role == models.RoleAdmin {
+		var count int64
+		if err := tx.Model(&models.Project{}).Where("id = ?", *t.ProjectID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return newHTTPError(http.StatusBadRequest, "Проект не найден")
+		}
+		return nil
+	}
+	projectRole, err := projectRole(tx, *t.ProjectID, userID)
+	if err != nil {
+		return err
+	}
+	switch projectRole {
+	case models.ProjectRoleEditor, models.ProjectRoleOwner:
+		return nil
+	case models.ProjectRoleViewer:
+		return newHTTPError(http.StatusForbidden, "Недостаточно прав для добавления задач в проект")
+	default:
+		return newHTTPError(http.StatusBadRequest, "Проект не найден")
+	}
+}
+
+// findTask загружает задачу с учётом прав пользователя.
+// Чужая задача для пользователя неотличима от несуществующей.
+func findTask(id, userID int, role string) (models.Task, bool) {
+	return findTaskIn(db.DB, id, userID, role)
+}
+
+// findTaskIn — findTask внутри транзакции
+func findTaskIn(tx *gorm.DB, id, userID int, role string) (models.Task, bool) {
+	var t models.Task
+	if err := taskScope(tx.Model(&models.Task{}), userID, role, true).Preload("Tags").Preload("Assignees").First(&t, id).Error; err != nil {
+		return t, false
+	}
+	return t, true
+}
+
+// insertTask проверяет и сохраняет новую задачу владельца ownerID вместе с
+// метками, исполнителями и записью в истории. Действие выполняет userID:
+// он указывается в истории и как назначивший исполнителей.
+func insertTask(tx *gorm.DB, t *models.Task, ownerID, userID int, role string) error {
+	t.ID = 0
+	t.UserID = ownerID
+	t.Version = 1
+	t.NextTaskID = nil
+	if err := resolveTaskStatus(nil, t); err != nil {
+		return err
+	}
+	if err := validate.Struct(t); err != nil {
+		return newHTTPError(http.StatusBadRequest, "%s", err.Error())
+	}
+	tags, assignees := t.Tags, t.Assignees
+	if err := validateTaskProject(tx, *t, nil, userID, role); err != nil {
+		return err
+	}
+	if err := validateTaskParent(tx, *t); err != nil {
+		return err
+	}
+	// Новая задача встаёт в конец ручного порядка
+	position, err := nextTaskPosition(tx, *t)
+	if err != nil {
+		return err
+	}
+	t.Position = position
+	if err := tx.Omit("Tags", "Assignees").Create(t).Error; err != nil {
+		return err
+	}
+	if err := replaceTaskTags(tx, t, tags); err != nil {
+		return err
+	}
+	if assignees == nil {
+		assignees = []models.TaskAssignee{}
+	}
+	if err := replaceTaskAssignees(tx, t, assignees, userID); err != nil {
+		return err
+	}
+	return recordTaskChange(tx, userID, nil, *t)
+}
+
+// patchTask применяет к задаче merge patch. Права на изменение проверяет
+// вызывающий код.
+func patchTask(tx *gorm.DB, existing models.Task, patch []byte, userID int, role string) (models.Task, error) {
+	t, columns, err := applyTaskPatch(existing, patch)
+	if err != nil {
+		return existing, newHTTPError(http.StatusBadRequest, "Некорректный запрос")
+	}
+	if err := resolveTaskStatus(&existing, &t); err != nil {
+		return existing, err
+	}
+	// Статус и done сохраняются вместе, даже если в патче было одно из них
+	for _, column := range []string{"done", "status"} {
+		if !slices.Contains(columns, column) {
+			columns = append(columns, column)
+		}
+	}
+	if err := validate.Struct(t); err != nil {
+		return existing, newHTTPError(http.StatusBadRequest, "%s", err.Error())
+	}
+	tags, assignees := t.Tags, t.Assignees
+	if err := checkAssigneeChange(existing, assignees, userID, role); err != nil {
+		return existing, err
+	}
+	if err := validateTaskProject(tx, t, existing.ProjectID, userID, role); err != nil {
+		return existing, err
+	}
+	if err := validateTaskParent(tx, t); err != nil {
+		return existing, err
+	}
+	if !sameTaskList(existing, t) {
+		if t.Position, err = nextTaskPosition(tx, t); err != nil {
+			return existing, err
+		}
+		columns = append(columns, "position")
+	}
+	// Обновляем только переданные поля, не трогая остальные колонки.
+	// Версия растёт и тогда, когда изменились одни метки.
+	if err := saveTaskVersion(tx, &t, existing.Version, columns...); err != nil {
+		return existing, err
+	}
+	if err := replaceTaskTags(tx, &t, tags); err != nil {
+		return existing, err
+	}
+	if err := replaceTaskAssignees(tx, &t, assignees, userID); err != nil {
+		return existing, err
+	}
+	if err := afterTaskUpdate(tx, userID, existing, &t); err != nil {
+		return existing, err
+	}
+	if t.Tags == nil {
+		t.Tags = existing.Tags
+	}
+	if t.Assignees == nil {
+		t.Assignees = existing.Assignees
+	}
+	return t, nil
+}
+
+// Поля задачи, которые заполняет только сервер
+var taskServerFields = map[string]bool{
+	"id":           true,
+	"user_id":      true,
+	"created_at":   true,
+	"updated_at":   true,
+	"version":      true,
+	"position":     true,
+	"next_task_id": true,
+	"rank":         true,
+	"highlight":    true,
+}
+
+// applyTaskPatch применяет JSON Merge Patch к задаче и возвращает
+// результат вместе со списком колонок, которые нужно обновить
+func applyTaskPatch(existing models.Task, patch []byte) (models.Task, []string, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(patch, &fields); err != nil {
+		return existing, nil, err
+	}
+	if fields == nil {
+		return existing, nil, errors.New("патч должен быть JSON-объектом")
+	}
+	for key := range fields {
+		if taskServerFields[key] {
+			delete(fields, key)
+		}
+	}
+	patch, _ = json.Marshal(fields)
+
+	current, err := json.Marshal(existing)
+	if err != nil {
+		return existing, nil, err
+	}
+	merged, err := mergepatch.Apply(current, patch)
+	if err != nil {
+		return existing, nil, err
+	}
+	var t models.Task
+	if err := json.Unmarshal(merged, &t); err != nil {
+		return existing, nil, err
+	}
+	t.ID = existing.ID
+	t.UserID = existing.UserID
+	t.CreatedAt = existing.CreatedAt
+	// Не переданные метки и исполнители остаются без изменений, а null,
+	// который merge patch просто удаляет из документа, очищает их
+	switch raw, ok := fields["tags"]; {
+	case !ok:
+		t.Tags = nil
+	case string(raw) == "null":
+		t.Tags = []models.Tag{}
+	}
+	switch raw, ok := fields["assignees"]; {
+	case !ok:
+		t.Assignees = nil
+	case string(raw) == "null":
+		t.Assignees = []models.TaskAssignee{}
+	}
+
+	stmt := &gorm.Statement{DB: db.DB}
+	if err := stmt.Parse(&models.Task{}); err != nil {
+		return existing, nil, err
+	}
+	var columns []string
+	for key := range fields {
+		if field := stmt.Schema.LookUpField(key); field != nil && field.DBName != "" {
+			columns = append(columns, field.DBName)
+		}
 	}
-	return true
+	sort.Strings(columns)
+	return t, columns, nil
 }
@@ -9,6 +9,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 	"todo-api/internal/handlers"
 	"todo-api/internal/models"
 	"todo-api/pkg/db"
@@ -48,7 +49,7 @@ func SeedTasks(count int) []models.Task {
 
 func TestGetTasksForUser(t *testing.T) {
 	// Инициализируем тестовую базу и сохраняем имя схемы
-	schemaName := db.InitTestDB()
+	schemaName := initTestDB()
 	defer func() {
 		// Очищаем тестовую схему после теста
 		db.DB.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schemaName))
@@ -136,6 +137,7 @@ func TestGetTask(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Ошибка создания запроса: %v", err)
 	}
+	req.Header.Set("UserID", "1")
 
 	// Создаём ResponseRecorder
 	rr := httptest.NewRecorder()
@@ -318,6 +320,7 @@ func TestUpdateTask(t *testing.T) {
 	}
 
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("UserID", "1")
 
 	// Создаём ResponseRecorder
 	rr := httptest.NewRecorder()
@@ -400,6 +403,7 @@ func TestDeleteTask(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Ошибка создания запроса: %v", err)
 	}
+	req.Header.Set("UserID", "1")
 
 	// Создаём ResponseRecorder
 	rr := httptest.NewRecorder()
@@ -420,10 +424,201 @@ func TestDeleteTask(t *testing.T) {
 	}
 }
 
-func TestTasksHandler_Get_Pagination(t *testing.T) {
+func TestTaskHandler_ForeignTask(t *testing.T) {
+	schemaName := db.InitTestDB()
+	defer db.DB.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schemaName))
+
+	// Задача принадлежит пользователю 1
+	task := SeedTasks(1)[0]
+	url := fmt.Sprintf("/tasks/%d", task.ID)
+
+	tests := []struct {
+		name       string
+		method     string
+		userID     string
+		role       string
+		body       string
+		wantStatus int
+	}{
+		{"Чужой GET", "GET", "2", models.RoleUser, "", http.StatusNotFound},
+		{"Чужой PUT", "PUT", "2", models.RoleUser, `{"title":"hijacked"}`, http.StatusNotFound},
+		{"Чужой DELETE", "DELETE", "2", models.RoleUser, "", http.StatusNotFound},
+		{"Администратор GET", "GET", "2", models.RoleAdmin, "", http.StatusOK},
+		{"Администратор PUT", "PUT", "2", models.RoleAdmin, `{"title":"by admin"}`, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, url, strings.NewReader(tt.body))
+			req.Header.Set("UserID", tt.userID)
+			req.Header.Set("Role", tt.role)
+			rr := httptest.NewRecorder()
+
+			handlers.TaskHandler(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("Ожидался статус %v, получен %v", tt.wantStatus, rr.Code)
+			}
+		})
+	}
+
+	// Задача по-прежнему принадлежит владельцу
+	var saved models.Task
+	if err := db.DB.First(&saved, task.ID).Error; err != nil {
+		t.Fatalf("Задача не найдена в базе: %v", err)
+	}
+	if saved.UserID != task.UserID {
+		t.Errorf("Ожидался UserID %d, получен %d", task.UserID, saved.UserID)
+	}
+}
+
+func TestTaskHandler_IfMatch(t *testing.T) {
 	schemaName := db.InitTestDB()
 	defer db.DB.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schemaName))
 
+	task := createTask(t, 1, `{"title":"Shared draft"}`)
+	url := fmt.Sprintf("/tasks/%d", task.ID)
+
+	do := func(method, ifMatch, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("UserID", "1")
+		req.Header.Set("Role", models.RoleUser)
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		rr := httptest.NewRecorder()
+		handlers.TaskHandler(rr, req)
+		return rr
+	}
+
+	etag := do("GET", "", "").Header().Get("ETag")
+	if etag != `"1"` {
+		t.Fatalf("Ожидался ETag %q, получен %q", `"1"`, etag)
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		ifMatch    string
+		body       string
+		wantStatus int
+		wantETag   string
+	}{
+		{"Актуальная версия", "PATCH", etag, `{"title":"First edit"}`, http.StatusOK, `"2"`},
+		{"Устаревшая версия", "PATCH", etag, `{"title":"Second edit"}`, http.StatusPreconditionFailed, ""},
+		{"PUT с устаревшей версией", "PUT", etag, `{"title":"Second edit"}`, http.StatusPreconditionFailed, ""},
+		{"Любая версия", "PUT", "*", `{"title":"Second edit"}`, http.StatusOK, `"3"`},
+		{"Без If-Match", "PATCH", "", `{"done":true}`, http.StatusOK, `"4"`},
+		{"DELETE с устаревшей версией", "DELETE", `"3"`, "", http.StatusPreconditionFailed, ""},
+		{"DELETE с актуальной версией", "DELETE", `"2", "4"`, "", http.StatusNoContent, ""},
+	}
+	for _, tt := range tests {
+		rr := do(tt.method, tt.ifMatch, tt.body)
+		if rr.Code != tt.wantStatus {
+			t.Errorf("%s: ожидался статус %v, получен %v", tt.name, tt.wantStatus, rr.Code)
+		}
+		if got := rr.Header().Get("ETag"); tt.wantETag != "" && got != tt.wantETag {
+			t.Errorf("%s: ожидался ETag %q, получен %q", tt.name, tt.wantETag, got)
+		}
+	}
+}
+
+func TestPatchTask(t *testing.T) {
+	schemaName := db.InitTestDB()
+	defer db.DB.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schemaName))
+
+	// Task A: Done = true, UserID = 1
+	task := SeedTasks(1)[0]
+	url := fmt.Sprintf("/tasks/%d", task.ID)
+
+	body := `{"title":"patched title","user_id":42,"id":100}`
+	req, _ := http.NewRequest("PATCH", url, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	req.Header.Set("UserID", "1")
+	rr := httptest.NewRecorder()
+
+	handlers.TaskHandler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус %v, получен %v: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	var saved models.Task
+	if err := db.DB.First(&saved, task.ID).Error; err != nil {
+		t.Fatalf("Задача не найдена в базе: %v", err)
+	}
+	if saved.Title != "patched title" {
+		t.Errorf("Ожидался Title %q, получен %q", "patched title", saved.Title)
+	}
+	if saved.Done != task.Done {
+		t.Errorf("Поле Done не должно меняться: ожидалось %v, получено %v", task.Done, saved.Done)
+	}
+	if saved.UserID != task.UserID {
+		t.Errorf("Поле UserID не должно меняться: ожидалось %d, получено %d", task.UserID, saved.UserID)
+	}
+
+	// Удаление обязательного поля не проходит валидацию
+	req, _ = http.NewRequest("PATCH", url, strings.NewReader(`{"title":null}`))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	req.Header.Set("UserID", "1")
+	rr = httptest.NewRecorder()
+
+	handlers.TaskHandler(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Ожидался статус %v, получен %v", http.StatusBadRequest, rr.Code)
+	}
+}
+
+func TestPatchTask_NullClearsTagsAndAssignees(t *testing.T) {
+	schemaName := db.InitTestDB()
+	defer db.DB.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schemaName))
+
+	users := []models.User{
+		{Username: "owner", Password: "hashed", Role: models.RoleUser},
+		{Username: "worker", Password: "hashed", Role: models.RoleUser},
+	}
+	db.DB.Create(&users)
+	task := createTask(t, users[0].ID, fmt.Sprintf(`{"title":"Prepare release","tags":["work"],"assignees":[{"user_id":%d}]}`, users[1].ID))
+
+	tests := []struct {
+		name          string
+		body          string
+		wantTags      int
+		wantAssignees int
+	}{
+		{"Без полей", `{"title":"Prepare the release"}`, 1, 1},
+		{"Метки null", `{"tags":null}`, 0, 1},
+		{"Исполнители null", `{"assignees":null}`, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("PATCH", fmt.Sprintf("/tasks/%d", task.ID), strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/merge-patch+json")
+			req.Header.Set("UserID", fmt.Sprintf("%d", users[0].ID))
+			req.Header.Set("Role", models.RoleUser)
+			rr := httptest.NewRecorder()
+
+			handlers.TaskHandler(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Ожидался статус %v, получен %v: %s", http.StatusOK, rr.Code, rr.Body.String())
+			}
+			var saved models.Task
+			db.DB.Preload("Tags").Preload("Assignees").First(&saved, task.ID)
+			if len(saved.Tags) != tt.wantTags || len(saved.Assignees) != tt.wantAssignees {
+				t.Errorf("Ожидалось меток %d и исполнителей %d, получено %d и %d",
+					tt.wantTags, tt.wantAssignees, len(saved.Tags), len(saved.Assignees))
+			}
+		})
+	}
+}
+
+func TestTasksHandler_Get_Pagination(t *testing.T) {
+	schemaName := initTestDB()
+	defer db.DB.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schemaName))
+
 	user := models.User{Username: "testuser", Password: "hashed", Role: models.RoleUser}
 	db.DB.Create(&user)
 
@@ -471,8 +666,436 @@ func TestTasksHandler_Get_Pagination(t *testing.T) {
 	}
 }
 
-func BenchmarkTasksHandler_Get(b *testing.B) {
+func TestTasksHandler_Get_DueDateFilters(t *testing.T) {
+	schemaName := initTestDB()
+	defer db.DB.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schemaName))
+
+	user := models.User{Username: "testuser", Password: "hashed", Role: models.RoleUser}
+	db.DB.Create(&user)
+
+	past := models.DueDate{Time: time.Now().Add(-48 * time.Hour)}
+	future := models.DueDate{Time: time.Now().Add(48 * time.Hour)}
+	tasks := []models.Task{
+		{Title: "Overdue task", UserID: user.ID, DueDate: &past},
+		{Title: "Done past task", Done: true, UserID: user.ID, DueDate: &past},
+		{Title: "Future task", UserID: user.ID, DueDate: &future},
+		{Title: "No due date", UserID: user.ID},
+	}
+	for i := range tasks {
+		db.DB.Create(&tasks[i])
+	}
+
+	today := time.Now().UTC().Format(time.DateOnly)
+	tests := []struct {
+		name       string
+		query      string
+		wantCount  int
+		wantStatus int
+	}{
+		{"Overdue", "?overdue=true", 1, http.StatusOK},
+		{"Not overdue", "?overdue=false", 3, http.StatusOK},
+		{"Due before today", "?due_before=" + today, 2, http.StatusOK},
+		{"Due after today", "?due_after=" + today, 1, http.StatusOK},
+		{"Invalid date", "?due_before=tomorrow", 0, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/tasks"+tt.query, nil)
+			req.Header.Set("UserID", fmt.Sprintf("%d", user.ID))
+			req.Header.Set("Role", models.RoleUser)
+			rr := httptest.NewRecorder()
+
+			handlers.TasksHandler(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("Ожидался статус %v, получен %v", tt.wantStatus, rr.Code)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var got []models.Task
+			if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+				t.Fatalf("Ошибка десериализации: %v", err)
+			}
+			if len(got) != tt.wantCount {
+				t.Errorf("Ожидалось %d задач, получено %d", tt.wantCount, len(got))
+			}
+		})
+	}
+}
+
+func TestTasksHandler_Get_Sort(t *testing.T) {
+	schemaName := initTestDB()
+	defer db.DB.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schemaName))
+
+	// Task A (done), Task B, Task C (done)
+	SeedTasks(3)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantTitles []string
+		wantStatus int
+	}{
+		{"Title desc", "?sort=-title", []string{"Task C", "Task B", "Task A"}, http.StatusOK},
+		{"Done then title desc", "?sort=done,-title", []string{"Task B", "Task C", "Task A"}, http.StatusOK},
+		{"Unknown field", "?sort=password", nil, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/tasks"+tt.query, nil)
+			req.Header.Set("UserID", "1")
+			req.Header.Set("Role", models.RoleUser)
+			rr := httptest.NewRecorder()
+
+			handlers.TasksHandler(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("Ожидался статус %v, получен %v", tt.wantStatus, rr.Code)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var got []models.Task
+			if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+				t.Fatalf("Ошибка десериализации: %v", err)
+			}
+			if len(got) != len(tt.wantTitles) {
+				t.Fatalf("Ожидалось %d задач, получено %d", len(tt.wantTitles), len(got))
+			}
+			for i, task := range got {
+				if task.Title != tt.wantTitles[i] {
+					t.Errorf("Позиция %d: ожидалась задача %q, получена %q", i, tt.wantTitles[i], task.Title)
+				}
+			}
+		})
+	}
+}
+
+func TestTasksHandler_Tags(t *testing.T) {
+	schemaName := initTestDB()
+	defer db.DB.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schemaName))
+
+	bodies := []string{
+		`{"title":"Write report","tags":["work","urgent"]}`,
+		`{"title":"Review PR","tags":["work"]}`,
+		`{"title":"Buy milk","tags":["home"]}`,
+	}
+	for _, body := range bodies {
+		req, _ := http.NewRequest("POST", "/tasks", strings.NewReader(body))
+		req.Header.Set("UserID", "1")
+		req.Header.Set("Role", models.RoleUser)
+		rr := httptest.NewRecorder()
+
+		handlers.TasksHandler(rr, req)
+
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("Ожидался статус %v, получен %v: %s", http.StatusCreated, rr.Code, rr.Body.String())
+		}
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		wantCount int
+	}{
+		{"Any of work, urgent", "?tag=work&tag=urgent", 2},
+		{"All of work, urgent", "?tag=work&tag=urgent&tag_match=all", 1},
+		{"Home", "?tag=home", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/tasks"+tt.query, nil)
+			req.Header.Set("UserID", "1")
+			req.Header.Set("Role", models.RoleUser)
+			rr := httptest.NewRecorder()
+
+			handlers.TasksHandler(rr, req)
+
+			var got []models.Task
+			if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+				t.Fatalf("Ошибка десериализации: %v", err)
+			}
+			if len(got) != tt.wantCount {
+				t.Errorf("Ожидалось %d задач, получено %d", tt.wantCount, len(got))
+			}
+		})
+	}
+
+	// Список меток с количеством использований
+	req, _ := http.NewRequest("GET", "/tags", nil)
+	req.Header.Set("UserID", "1")
+	req.Header.Set("Role", models.RoleUser)
+	rr := httptest.NewRecorder()
+
+	handlers.TagsHandler(rr, req)
+
+	var usage []struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&usage); err != nil {
+		t.Fatalf("Ошибка десериализации: %v", err)
+	}
+	want := map[string]int{"home": 1, "urgent": 1, "work": 2}
+	if len(usage) != len(want) {
+		t.Fatalf("Ожидалось %d меток, получено %d", len(want), len(usage))
+	}
+	for _, u := range usage {
+		if want[u.Name] != u.Count {
+			t.Errorf("Метка %s: ожидалось %d задач, получено %d", u.Name, want[u.Name], u.Count)
+		}
+	}
+}
+
+func TestTasksHandler_Search(t *testing.T) {
+	schemaName := initTestDB()
+	defer db.DB.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schemaName))
+
+	createTask(t, 1, `{"title":"Buy milk and bread"}`)
+	createTask(t, 1, `{"title":"Buy <b>milk</b>","done":true}`)
+	createTask(t, 1, `{"title":"Write report"}`)
+	createTask(t, 2, `{"title":"Buy milk"}`)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantTitles []string
+	}{
+		{"Single word", "?q=milk&sort=id", []string{"Buy milk and bread", "Buy <b>milk</b>"}},
+		{"Both words", "?q=milk+bread", []string{"Buy milk and bread"}},
+		{"Negation", "?q=buy+-bread", []string{"Buy <b>milk</b>"}},
+		{"With done filter", "?q=milk&done=false", []string{"Buy milk and bread"}},
+		{"No matches", "?q=unicorn", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/tasks"+tt.query, nil)
+			req.Header.Set("UserID", "1")
+			req.Header.Set("Role", models.RoleUser)
+			rr := httptest.NewRecorder()
+
+			handlers.TasksHandler(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Ожидался статус %v, получен %v", http.StatusOK, rr.Code)
+			}
+			var got []models.Task
+			if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+				t.Fatalf("Ошибка десериализации: %v", err)
+			}
+			if len(got) != len(tt.wantTitles) {
+				t.Fatalf("Ожидалось %d задач, получено %d", len(tt.wantTitles), len(got))
+			}
+			for i, task := range got {
+				if task.Title != tt.wantTitles[i] {
+					t.Errorf("Позиция %d: ожидалась задача %q, получена %q", i, tt.wantTitles[i], task.Title)
+				}
+				if task.Rank <= 0 {
+					t.Errorf("Задача %q: ожидалась положительная релевантность", task.Title)
+				}
+			}
+		})
+	}
+
+	// Подсветка экранирует разметку из заголовка
+	req, _ := http.NewRequest("GET", "/tasks?q=milk&done=true&highlight=true", nil)
+	req.Header.Set("UserID", "1")
+	req.Header.Set("Role", models.RoleUser)
+	rr := httptest.NewRecorder()
+
+	handlers.TasksHandler(rr, req)
+
+	var got []models.Task
+	if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+		t.Fatalf("Ошибка десериализации: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("Ожидалась 1 задача, получено %d", len(got))
+	}
+	want := "Buy &lt;b&gt;<mark>milk</mark>&lt;/b&gt;"
+	if got[0].Highlight != want {
+		t.Errorf("Ожидалась подсветка %q, получена %q", want, got[0].Highlight)
+	}
+}
+
+func TestTasksHandler_Get_Cursor(t *testing.T) {
+	schemaName := initTestDB()
+	defer db.DB.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schemaName))
+
+	// Задачи без срока идут в конце, как и при обычной сортировке
+	createTask(t, 1, `{"title":"Task A","due_date":"2030-01-03"}`)
+	createTask(t, 1, `{"title":"Task B"}`)
+	createTask(t, 1, `{"title":"Task C","due_date":"2030-01-01"}`)
+	createTask(t, 1, `{"title":"Task D","due_date":"2030-01-03"}`)
+	createTask(t, 1, `{"title":"Task E"}`)
+
+	get := func(query string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/tasks"+query, nil)
+		req.Header.Set("UserID", "1")
+		req.Header.Set("Role", models.RoleUser)
+		rr := httptest.NewRecorder()
+		handlers.TasksHandler(rr, req)
+		return rr
+	}
+
+	var titles []string
+	cursor := ""
+	for pages := 0; pages < 5; pages++ {
+		rr := get("?sort=due_date&limit=2&cursor=" + cursor)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Ожидался статус %v, получен %v: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+		var tasks []models.Task
+		if err := json.NewDecoder(rr.Body).Decode(&tasks); err != nil {
+			t.Fatalf("Ошибка десериализации: %v", err)
+		}
+		for _, task := range tasks {
+			titles = append(titles, task.Title)
+		}
+		if pages == 0 {
+			// Новая задача в начале списка не сдвигает следующие страницы
+			createTask(t, 1, `{"title":"Task F","due_date":"2029-01-01"}`)
+		}
+		if cursor = rr.Header().Get("X-Next-Cursor"); cursor == "" {
+			break
+		}
+	}
+	want := []string{"Task C", "Task A", "Task D", "Task B", "Task E"}
+	if strings.Join(titles, ",") != strings.Join(want, ",") {
+		t.Errorf("Ожидался порядок %v, получен %v", want, titles)
+	}
+
+	first := get("?sort=due_date&limit=2&cursor=")
+	next := first.Header().Get("X-Next-Cursor")
+	if rr := get("?sort=-title&limit=2&cursor=" + next); rr.Code != http.StatusBadRequest {
+		t.Errorf("Курсор другой сортировки: ожидался статус %v, получен %v", http.StatusBadRequest, rr.Code)
+	}
+	if rr := get("?cursor=garbage"); rr.Code != http.StatusBadRequest {
+		t.Errorf("Некорректный курсор: ожидался статус %v, получен %v", http.StatusBadRequest, rr.Code)
+	}
+}
+
+func TestTasksHandler_Get_Envelope(t *testing.T) {
+	schemaName := initTestDB()
+	defer db.DB.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schemaName))
+
+	SeedTasks(5)
+
+	req, _ := http.NewRequest("GET", "/tasks?page=2&limit=2&envelope=true", nil)
+	req.Header.Set("UserID", "1")
+	req.Header.Set("Role", models.RoleUser)
+	rr := httptest.NewRecorder()
+
+	handlers.TasksHandler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус %v, получен %v", http.StatusOK, rr.Code)
+	}
+	if got := rr.Header().Get("X-Total-Count"); got != "5" {
+		t.Errorf("Ожидался X-Total-Count 5, получен %q", got)
+	}
+	link := rr.Header().Get("Link")
+	for _, want := range []string{
+		`</tasks?envelope=true&limit=2&page=1>; rel="first"`,
+		`</tasks?envelope=true&limit=2&page=1>; rel="prev"`,
+		`</tasks?envelope=true&limit=2&page=3>; rel="next"`,
+		`</tasks?envelope=true&limit=2&page=3>; rel="last"`,
+	} {
+		if !strings.Contains(link, want) {
+			t.Errorf("В заголовке Link нет %s: %s", want, link)
+		}
+	}
+
+	var envelope struct {
+		Items   []models.Task `json:"items"`
+		Page    int           `json:"page"`
+		Limit   int           `json:"limit"`
+		Total   int64         `json:"total"`
+		HasNext bool          `json:"has_next"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&envelope); err != nil {
+		t.Fatalf("Ошибка десериализации: %v", err)
+	}
+	if len(envelope.Items) != 2 || envelope.Page != 2 || envelope.Limit != 2 || envelope.Total != 5 || !envelope.HasNext {
+		t.Errorf("Неверные метаданные страницы: %+v", envelope)
+	}
+}
+
+func TestTasksHandler_Get_NotModified(t *testing.T) {
+	schemaName := initTestDB()
+	defer db.DB.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schemaName))
+
+	SeedTasks(3)
+
+	get := func(url, ifNoneMatch string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", url, nil)
+		req.Header.Set("UserID", "1")
+		req.Header.Set("Role", models.RoleUser)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rr := httptest.NewRecorder()
+		if strings.HasPrefix(url, "/tasks/") {
+			handlers.TaskHandler(rr, req)
+		} else {
+			handlers.TasksHandler(rr, req)
+		}
+		return rr
+	}
+
+	first := get("/tasks?limit=2", "")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" {
+		t.Fatalf("Ожидался статус %v с ETag, получен %v, ETag %q", http.StatusOK, first.Code, etag)
+	}
+	if rr := get("/tasks?limit=2", etag); rr.Code != http.StatusNotModified || rr.Body.Len() != 0 {
+		t.Errorf("Список: ожидался статус %v без тела, получен %v", http.StatusNotModified, rr.Code)
+	}
+	if rr := get("/tasks?limit=2", `W/`+etag); rr.Code != http.StatusNotModified {
+		t.Errorf("Слабый ETag: ожидался статус %v, получен %v", http.StatusNotModified, rr.Code)
+	}
+	if rr := get("/tasks?limit=2&envelope=true", etag); rr.Code != http.StatusOK {
+		t.Errorf("Другое представление: ожидался статус %v, получен %v", http.StatusOK, rr.Code)
+	}
+
+	var tasks []models.Task
+	json.NewDecoder(first.Body).Decode(&tasks)
+	url := fmt.Sprintf("/tasks/%d", tasks[0].ID)
+	if rr := get(url, `"1"`); rr.Code != http.StatusNotModified || rr.Header().Get("ETag") != `"1"` {
+		t.Errorf("Задача: ожидался статус %v, получен %v", http.StatusNotModified, rr.Code)
+	}
+	if rr := get(url, `"0", "7"`); rr.Code != http.StatusOK {
+		t.Errorf("Задача с другим ETag: ожидался статус %v, получен %v", http.StatusOK, rr.Code)
+	}
+
+	// Изменение задачи сбрасывает кэш списка, и прежний ETag устаревает
+	req, _ := http.NewRequest("PATCH", url, strings.NewReader(`{"title":"Renamed task"}`))
+	req.Header.Set("UserID", "1")
+	req.Header.Set("Role", models.RoleUser)
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	rr := httptest.NewRecorder()
+	handlers.TaskHandler(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("PATCH: ожидался статус %v, получен %v", http.StatusOK, rr.Code)
+	}
+	if rr := get("/tasks?limit=2", etag); rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "Renamed task") {
+		t.Errorf("После изменения: ожидался статус %v с новым названием, получен %v", http.StatusOK, rr.Code)
+	}
+}
+
+// initTestDB создаёт тестовую схему и сбрасывает кэш списков задач: ключи
+// кэша не зависят от схемы, и иначе тест прочитал бы списки предыдущего
+func initTestDB() string {
 	schemaName := db.InitTestDB()
+	handlers.InvalidateTaskLists()
+	return schemaName
+}
+
+func BenchmarkTasksHandler_Get(b *testing.B) {
+	schemaName := initTestDB()
 	defer db.DB.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schemaName))
 
 	user := models.User{Username: "testuser", Password: "hashed", Role: models.RoleUser}
@@ -517,4 +1140,4 @@ func BenchmarkTasksHandler_Post(b *testing.B) {
 			rr := httptest.NewRecorder()
 			handlers.TasksHandler(rr, req)
 	}
-}
\ No newline at end of file
+}
\ No newline at end of file
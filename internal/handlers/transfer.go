@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"todo-api/internal/models"
+	"todo-api/pkg/db"
+	"todo-api/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// Размер пачки задач, которые выгрузка держит в памяти одновременно
+	exportBatchSize = 500
+	// Максимальный размер загружаемого файла
+	maxImportSize = 50 << 20
+)
+
+// errImportDryRun откатывает транзакцию пробного импорта
+var errImportDryRun = errors.New("пробный импорт")
+
+// importProblem — запись, которая не была импортирована
+type importProblem struct {
+	Row    int    `json:"row"` // Номер записи, начиная с 1, без учёта заголовка CSV
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// importReport — итог импорта
+type importReport struct {
+	DryRun     bool            `json:"dry_run"`
+	Total      int             `json:"total"`
+	Created    int             `json:"created"`
+	Duplicates int             `json:"duplicates"`
+	Invalid    int             `json:"invalid"`
+	Problems   []importProblem `json:"problems"`
+}
+
+// taskFormat определяет формат по параметру format, а при его отсутствии —
+// по типу содержимого запроса
+func taskFormat(r *http.Request, contentType string) (string, error) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		mediaType, _, _ := mime.ParseMediaType(contentType)
+		for f, ct := range taskFormatContentTypes {
+			if t, _, _ := mime.ParseMediaType(ct); t == mediaType {
+				format = f
+			}
+		}
+	}
+	if format == "" {
+		format = formatJSON
+	}
+	if _, ok := taskFormatContentTypes[format]; !ok {
+		return "", fmt.Errorf("Неверный параметр format: ожидается csv, json или ndjson")
+	}
+	return format, nil
+}
+
+// Обработчик выгрузки задач: GET /tasks/export?format=csv|json|ndjson.
+// Пользователь выгружает свои задачи, администратор — задачи всех
+// пользователей или одного, указанного в user_id.
+func exportTasksHandler(w http.ResponseWriter, r *http.Request, userID int, role string) {
+	if r.Method != "GET" {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+	format, err := taskFormat(r, "")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	query := db.DB.Model(&models.Task{}).Preload("Tags").Preload("Assignees")
+	if role != models.RoleAdmin {
+		query = query.Where("tasks.user_id = ?", userID)
+	} else if s := r.URL.Query().Get("user_id"); s != "" {
+		ownerID, err := strconv.Atoi(s)
+		if err != nil {
+			http.Error(w, "Неверный параметр user_id", http.StatusBadRequest)
+			return
+		}
+		query = query.Where("tasks.user_id = ?", ownerID)
+	}
+
+	w.Header().Set("Content-Type", taskFormatContentTypes[format])
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="tasks.%s"`, format))
+	flusher, _ := w.(http.Flusher)
+	tw := newTaskWriter(format, w)
+
+	// Задачи читаются пачками, чтобы не держать всю выгрузку в памяти
+	var batch []models.Task
+	err = query.FindInBatches(&batch, exportBatchSize, func(tx *gorm.DB, _ int) error {
+		for _, t := range batch {
+			if err := tw.Write(t); err != nil {
+				return err
+			}
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	}).Error
+	if err == nil {
+		err = tw.Close()
+	}
+	if err != nil {
+		// Заголовки уже отправлены, поэтому выгрузка просто обрывается
+		logger.Log.Errorf("Ошибка выгрузки задач: %v", err)
+	}
+}
+
+// Обработчик загрузки задач: POST /tasks/import?format=csv|json|ndjson[&dry_run=true].
+// Некорректные записи и дубликаты пропускаются и перечисляются в отчёте,
+// dry_run выполняет все проверки и откатывает изменения.
+func importTasksHandler(w http.ResponseWriter, r *http.Request, userID int, role string) {
+	if r.Method != "POST" {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+	format, err := taskFormat(r, r.Header.Get("Content-Type"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	dryRun, err := parseBoolParam(r.URL.Query(), "dry_run")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	reader, err := newTaskReader(format, http.MaxBytesReader(w, r.Body, maxImportSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	report := importReport{DryRun: dryRun != nil && *dryRun, Problems: []importProblem{}}
+	var assigned []models.Task
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if assigned, err = importTasks(tx, reader, userID, role, &report); err != nil {
+			return err
+		}
+		if report.DryRun {
+			return errImportDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errImportDryRun) {
+		var he *httpError
+		var maxErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &he):
+			http.Error(w, he.Message, he.Status)
+		case errors.As(err, &maxErr):
+			http.Error(w, "Файл слишком большой", http.StatusRequestEntityTooLarge)
+		default:
+			writeError(w, err, "Ошибка импорта задач")
+		}
+		return
+	}
+	if !report.DryRun {
+		InvalidateTaskLists()
+		for _, t := range assigned {
+			notifyAssignees(t, userID, addedAssignees(nil, t))
+		}
+	}
+	json.NewEncoder(w).Encode(report)
+}
+
+// importTasks читает записи и сохраняет каждую в своей точке сохранения.
+// Задачи импортируются без иерархии и проектов: их идентификаторы в другом
+// окружении не совпадают. Администратор сохраняет владельца из user_id.
+// Исполнители переносятся из JSON и NDJSON, в CSV их нет; запись с
+// неизвестным исполнителем отклоняется, как и при создании задачи.
+// Возвращает созданные задачи с исполнителями, чтобы после фиксации
+// уведомить их.
+func importTasks(tx *gorm.DB, reader taskReader, userID int, role string, report *importReport) ([]models.Task, error) {
+	var created []models.Task
+	for row := 1; ; row++ {
+		rec, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return created, nil
+		}
+		report.Total++
+		var re *rowError
+		if errors.As(err, &re) {
+			report.Invalid++
+			report.Problems = append(report.Problems, importProblem{Row: row, Status: "invalid", Error: re.Error()})
+			continue
+		}
+		if err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				return nil, err
+			}
+			return nil, newHTTPError(http.StatusBadRequest, "Запись %d: %v", row, err)
+		}
+
+		ownerID := userID
+		if role == models.RoleAdmin && rec.UserID != 0 {
+			ownerID = rec.UserID
+		}
+		t := models.Task{
+			Title:        rec.Title,
+			Done:         rec.Done,
+			Status:       rec.Status,
+			DueDate:      rec.DueDate,
+			Tags:         rec.Tags,
+			AutoComplete: rec.AutoComplete,
+			RRule:        rec.RRule,
+			Assignees:    rec.Assignees,
+		}
+
+		// Задачи, созданные ранее в этом же импорте, видны в транзакции
+		duplicate, err := taskDuplicateExists(tx, ownerID, t)
+		if err != nil {
+			return nil, err
+		}
+		if duplicate {
+			report.Duplicates++
+			report.Problems = append(report.Problems, importProblem{Row: row, Status: "duplicate"})
+			continue
+		}
+
+		err = tx.Transaction(func(tx *gorm.DB) error {
+			return insertTask(tx, &t, ownerID, userID, role)
+		})
+		var he *httpError
+		if errors.As(err, &he) {
+			report.Invalid++
+			report.Problems = append(report.Problems, importProblem{Row: row, Status: "invalid", Error: he.Message})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		report.Created++
+		if len(t.Assignees) > 0 {
+			created = append(created, t)
+		}
+	}
+}
+
+// taskDuplicateExists проверяет, есть ли у владельца задача с тем же
+// заголовком и сроком
+func taskDuplicateExists(tx *gorm.DB, ownerID int, t models.Task) (bool, error) {
+	query := tx.Model(&models.Task{}).Where("user_id = ? AND title = ?", ownerID, t.Title)
+	if t.DueDate == nil {
+		query = query.Where("due_date IS NULL")
+	} else {
+		query = query.Where("due_date = ?", t.DueDate.Time)
+	}
+	var count int64
+	err := query.Limit(1).Count(&count).Error
+	return count > 0, err
+}
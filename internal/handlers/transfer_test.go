@@ -0,0 +1,156 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"todo-api/internal/handlers"
+	"todo-api/internal/models"
+	"todo-api/pkg/db"
+)
+
+func TestExportTasks(t *testing.T) {
+	schemaName := db.InitTestDB()
+	defer db.DB.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schemaName))
+
+	createTask(t, 1, `{"title":"Write, report","due_date":"2030-01-02","tags":["work","urgent"]}`)
+	createTask(t, 1, `{"title":"Buy milk"}`)
+	createTask(t, 2, `{"title":"Foreign task"}`)
+
+	tests := []struct {
+		name        string
+		query       string
+		role        string
+		contentType string
+		wantLines   int
+		wantText    string
+	}{
+		{"CSV", "?format=csv", models.RoleUser, "text/csv; charset=utf-8", 3, `"Write, report",false,todo,2030-01-02,`},
+		{"NDJSON", "?format=ndjson", models.RoleUser, "application/x-ndjson", 2, `"title":"Buy milk"`},
+		{"JSON", "", models.RoleUser, "application/json", 1, `"due_date":"2030-01-02"`},
+		{"Admin NDJSON", "?format=ndjson", models.RoleAdmin, "application/x-ndjson", 3, `"title":"Foreign task"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/tasks/export"+tt.query, nil)
+			req.Header.Set("UserID", "1")
+			req.Header.Set("Role", tt.role)
+			rr := httptest.NewRecorder()
+
+			handlers.TaskHandler(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Ожидался статус %v, получен %v", http.StatusOK, rr.Code)
+			}
+			if got := rr.Header().Get("Content-Type"); got != tt.contentType {
+				t.Errorf("Ожидался Content-Type %q, получен %q", tt.contentType, got)
+			}
+			body := rr.Body.String()
+			if lines := strings.Count(body, "\n"); lines != tt.wantLines {
+				t.Errorf("Ожидалось %d строк, получено %d:\n%s", tt.wantLines, lines, body)
+			}
+			if !strings.Contains(body, tt.wantText) {
+				t.Errorf("В выгрузке нет %s:\n%s", tt.wantText, body)
+			}
+		})
+	}
+}
+
+func TestImportTasks(t *testing.T) {
+	schemaName := db.InitTestDB()
+	defer db.DB.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schemaName))
+
+	createTask(t, 1, `{"title":"Existing task"}`)
+
+	body := strings.Join([]string{
+		`{"title":"Imported task","due_date":"2030-01-02","tags":["home"]}`,
+		`{"title":"Existing task"}`,
+		`{"title":"Imported task","due_date":"2030-01-02"}`,
+		`{"title":"No"}`,
+		`not json`,
+		`{"title":"Another task","user_id":2}`,
+	}, "\n")
+
+	importTasks := func(query string) (int, map[string]interface{}) {
+		req, _ := http.NewRequest("POST", "/tasks/import"+query, strings.NewReader(body))
+		req.Header.Set("UserID", "1")
+		req.Header.Set("Role", models.RoleUser)
+		req.Header.Set("Content-Type", "application/x-ndjson")
+		rr := httptest.NewRecorder()
+
+		handlers.TaskHandler(rr, req)
+
+		var report map[string]interface{}
+		json.NewDecoder(rr.Body).Decode(&report)
+		return rr.Code, report
+	}
+	countTasks := func() int64 {
+		var n int64
+		db.DB.Model(&models.Task{}).Where("user_id = ?", 1).Count(&n)
+		return n
+	}
+
+	for _, query := range []string{"?dry_run=true", ""} {
+		code, report := importTasks(query)
+		if code != http.StatusOK {
+			t.Fatalf("%s: ожидался статус %v, получен %v", query, http.StatusOK, code)
+		}
+		want := map[string]float64{"total": 6, "created": 2, "duplicates": 2, "invalid": 2}
+		for key, value := range want {
+			if report[key] != value {
+				t.Errorf("%s: ожидалось %s = %v, получено %v", query, key, value, report[key])
+			}
+		}
+		if problems, _ := report["problems"].([]interface{}); len(problems) != 4 {
+			t.Errorf("%s: ожидалось 4 проблемные записи, получено %d", query, len(problems))
+		}
+	}
+	// Пробный импорт ничего не сохранил, обычный добавил две задачи к
+	// существующей. Чужой user_id обычному пользователю не доступен.
+	if n := countTasks(); n != 3 {
+		t.Errorf("Ожидалось 3 задачи, найдено %d", n)
+	}
+}
+
+func TestImportTasks_AdminForOtherUser(t *testing.T) {
+	schemaName := db.InitTestDB()
+	defer db.DB.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schemaName))
+
+	users := []models.User{
+		{Username: "admin", Password: "hashed", Role: models.RoleAdmin},
+		{Username: "worker", Password: "hashed", Role: models.RoleUser},
+	}
+	db.DB.Create(&users)
+	admin, worker := users[0].ID, users[1].ID
+
+	body := fmt.Sprintf(`{"title":"Imported for worker","user_id":%d,"assignees":[{"user_id":%d}]}`, worker, worker)
+	req, _ := http.NewRequest("POST", "/tasks/import?format=ndjson", strings.NewReader(body))
+	req.Header.Set("UserID", fmt.Sprintf("%d", admin))
+	req.Header.Set("Role", models.RoleAdmin)
+	rr := httptest.NewRecorder()
+
+	handlers.TaskHandler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус %v, получен %v: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	var task models.Task
+	if err := db.DB.Preload("Assignees").Where("title = ?", "Imported for worker").First(&task).Error; err != nil {
+		t.Fatalf("Задача не импортирована: %v", err)
+	}
+	if task.UserID != worker {
+		t.Errorf("Ожидался владелец %d, получен %d", worker, task.UserID)
+	}
+	// Владелец — пользователь из записи, а действие выполнил администратор
+	if len(task.Assignees) != 1 || task.Assignees[0].UserID != worker || task.Assignees[0].AssignedBy != admin {
+		t.Errorf("Ожидался исполнитель %d, назначенный %d, получено %+v", worker, admin, task.Assignees)
+	}
+	var history models.TaskHistory
+	db.DB.Where("task_id = ?", task.ID).First(&history)
+	if history.UserID != admin {
+		t.Errorf("В истории ожидался автор %d, получен %d", admin, history.UserID)
+	}
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"time"
+	"todo-api/internal/models"
+	"todo-api/pkg/db"
+	"todo-api/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultTrashRetention     = 30 * 24 * time.Hour
+	defaultTrashPurgeInterval = time.Hour
+)
+
+// trashedTask — задача из корзины вместе со временем удаления
+type trashedTask struct {
+	models.Task
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// Обработчик для корзины: /tasks/trash
+func trashHandler(w http.ResponseWriter, r *http.Request, userID int, role string) {
+	if r.Method != "GET" {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+	page, limit := parsePagination(r.URL.Query())
+	var tasks []models.Task
+	err := taskScope(db.DB.Unscoped().Model(&models.Task{}), userID, role, false).
+		Where("tasks.deleted_at IS NOT NULL").
+		Preload("Tags").
+		Preload("Assignees").
+		Order("tasks.deleted_at DESC, tasks.id").
+		Offset((page - 1) * limit).Limit(limit).
+		Find(&tasks).Error
+	if err != nil {
+		writeError(w, err, "Ошибка получения корзины")
+		return
+	}
+	trash := make([]trashedTask, 0, len(tasks))
+	for _, t := range tasks {
+		trash = append(trash, trashedTask{Task: t, DeletedAt: t.DeletedAt.Time})
+	}
+	json.NewEncoder(w).Encode(trash)
+}
+
+// Обработчик восстановления задачи: /tasks/{id}/restore
+func restoreTaskHandler(w http.ResponseWriter, r *http.Request, id, userID int, role string) {
+	if r.Method != "POST" {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+	var t models.Task
+	err := taskScope(db.DB.Unscoped().Model(&models.Task{}), userID, role, true).
+		Where("tasks.deleted_at IS NOT NULL").
+		First(&t, id).Error
+	if err != nil {
+		http.Error(w, "Задача не найдена в корзине", http.StatusNotFound)
+		return
+	}
+	if taskAccess(t, userID, role) < accessManage {
+		http.Error(w, "Недостаточно прав", http.StatusForbidden)
+		return
+	}
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		return restoreTaskTree(tx, userID, t)
+	})
+	if err != nil {
+		writeError(w, err, "Ошибка восстановления задачи")
+		return
+	}
+	InvalidateTaskLists()
+	restored, _ := findTask(id, userID, role)
+	json.NewEncoder(w).Encode(restored)
+}
+
+// PurgeTrash окончательно удаляет задачи, пролежавшие в корзине дольше
+// retention, вместе с их вложениями. Возвращает число удалённых задач.
+func PurgeTrash(retention time.Duration) (int, error) {
+	var ids []int
+	var keys []string
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Unscoped().Model(&models.Task{}).
+			Where("deleted_at < ?", time.Now().Add(-retention)).
+			Pluck("id", &ids).Error
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+		if keys, err = attachmentKeys(tx, ids); err != nil {
+			return err
+		}
+		// Ссылки на удаляемых родителей не должны пережить их
+		if err := tx.Unscoped().Model(&models.Task{}).Where("parent_id IN ?", ids).Update("parent_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&models.Task{}, ids).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	removeBlobs(keys)
+	return len(ids), nil
+}
+
+// StartTrashPurge запускает фоновую очистку корзины. Срок хранения и
+// период запуска задаются переменными TRASH_RETENTION и
+// TRASH_PURGE_INTERVAL в формате time.ParseDuration.
+func StartTrashPurge(ctx context.Context) {
+	retention := durationEnv("TRASH_RETENTION", defaultTrashRetention)
+	interval := durationEnv("TRASH_PURGE_INTERVAL", defaultTrashPurgeInterval)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if n, err := PurgeTrash(retention); err != nil {
+				logger.Log.Errorf("Ошибка очистки корзины: %v", err)
+			} else if n > 0 {
+				logger.Log.Infof("Из корзины удалено задач: %d", n)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func durationEnv(name string, fallback time.Duration) time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(name)); err == nil && d > 0 {
+		return d
+	}
+	return fallback
+}
@@ -0,0 +1,85 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"todo-api/internal/handlers"
+	"todo-api/internal/models"
+	"todo-api/pkg/db"
+)
+
+func TestTrash(t *testing.T) {
+	schemaName := db.InitTestDB()
+	defer db.DB.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schemaName))
+
+	parent := createTask(t, 1, `{"title":"Parent task"}`)
+	child := createTask(t, 1, fmt.Sprintf(`{"title":"Child task","parent_id":%d}`, parent.ID))
+
+	do := func(method, url, userID string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, nil)
+		req.Header.Set("UserID", userID)
+		req.Header.Set("Role", models.RoleUser)
+		rr := httptest.NewRecorder()
+		handlers.TaskHandler(rr, req)
+		return rr
+	}
+
+	if rr := do("DELETE", fmt.Sprintf("/tasks/%d?children=cascade", parent.ID), "1"); rr.Code != http.StatusNoContent {
+		t.Fatalf("Ожидался статус %v, получен %v", http.StatusNoContent, rr.Code)
+	}
+	if rr := do("GET", fmt.Sprintf("/tasks/%d", child.ID), "1"); rr.Code != http.StatusNotFound {
+		t.Errorf("Подзадача в корзине: ожидался статус %v, получен %v", http.StatusNotFound, rr.Code)
+	}
+
+	// Корзина видна только владельцу
+	for userID, want := range map[string]int{"1": 2, "2": 0} {
+		rr := do("GET", "/tasks/trash", userID)
+		var trash []struct {
+			ID        int    `json:"id"`
+			DeletedAt string `json:"deleted_at"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&trash); err != nil {
+			t.Fatalf("Ошибка десериализации: %v", err)
+		}
+		if len(trash) != want {
+			t.Errorf("Пользователь %s: ожидалось %d задач в корзине, получено %d", userID, want, len(trash))
+		}
+		for _, item := range trash {
+			if item.DeletedAt == "" {
+				t.Errorf("Задача %d: не указано время удаления", item.ID)
+			}
+		}
+	}
+
+	// Подзадачу нельзя восстановить раньше родителя
+	if rr := do("POST", fmt.Sprintf("/tasks/%d/restore", child.ID), "1"); rr.Code != http.StatusConflict {
+		t.Errorf("Ожидался статус %v, получен %v", http.StatusConflict, rr.Code)
+	}
+	if rr := do("POST", fmt.Sprintf("/tasks/%d/restore", parent.ID), "2"); rr.Code != http.StatusNotFound {
+		t.Errorf("Чужая задача: ожидался статус %v, получен %v", http.StatusNotFound, rr.Code)
+	}
+	if rr := do("POST", fmt.Sprintf("/tasks/%d/restore", parent.ID), "1"); rr.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус %v, получен %v: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	if rr := do("GET", fmt.Sprintf("/tasks/%d", child.ID), "1"); rr.Code != http.StatusOK {
+		t.Errorf("Подзадача должна восстановиться вместе с родителем, получен статус %v", rr.Code)
+	}
+
+	// Очистка удаляет задачи окончательно
+	do("DELETE", fmt.Sprintf("/tasks/%d?children=cascade", parent.ID), "1")
+	n, err := handlers.PurgeTrash(0)
+	if err != nil {
+		t.Fatalf("Ошибка очистки корзины: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("Ожидалось удалить 2 задачи, удалено %d", n)
+	}
+	var left int64
+	db.DB.Unscoped().Model(&models.Task{}).Count(&left)
+	if left != 0 {
+		t.Errorf("После очистки осталось задач: %d", left)
+	}
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// TaskAssignee — исполнитель задачи. Создатель задачи хранится в Task.UserID
+// и исполнителем автоматически не становится.
+type TaskAssignee struct {
+	TaskID     int       `json:"-" gorm:"primaryKey"`
+	UserID     int       `json:"user_id" gorm:"primaryKey;index" validate:"required"`
+	AssignedBy int       `json:"assigned_by"`
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime,default:now()"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Attachment — файл, прикреплённый к задаче. Само содержимое лежит
+// в хранилище storage под ключом StorageKey.
+type Attachment struct {
+	ID          int       `json:"id" gorm:"primaryKey"`
+	TaskID      int       `json:"task_id" gorm:"index"`
+	Task        *Task     `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	UserID      int       `json:"user_id"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	StorageKey  string    `json:"-" gorm:"uniqueIndex"`
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime,default:now()"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// CalendarToken — секрет ссылки на календарь задач пользователя. В базе
+// хранится только SHA-256 токена, сам токен показывается один раз.
+type CalendarToken struct {
+	ID        int       `json:"id" gorm:"primaryKey"`
+	UserID    int       `json:"user_id" gorm:"uniqueIndex"`
+	TokenHash string    `json:"-" gorm:"uniqueIndex"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime,default:now()"`
+}
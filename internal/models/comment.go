@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// Comment — комментарий к задаче
+type Comment struct {
+	ID        int       `json:"id" gorm:"primaryKey"`
+	TaskID    int       `json:"task_id" gorm:"index"`
+	Task      *Task     `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	UserID    int       `json:"user_id" gorm:"index"`
+	User      *User     `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	Body      string    `json:"body" validate:"required,max=5000"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime,default:now()"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime,default:now()"`
+}
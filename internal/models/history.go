@@ -0,0 +1,65 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// TaskHistory — запись журнала изменений задачи. Внешнего ключа на задачу
+// нет: журнал переживает окончательное удаление задачи из корзины.
+type TaskHistory struct {
+	ID        int          `json:"id" gorm:"primaryKey"`
+	TaskID    int          `json:"task_id" gorm:"index"`
+	UserID    int          `json:"user_id"`                             // Кто внёс изменение
+	Action    string       `json:"action"`                              // create, update, delete или restore
+	Changes   FieldChanges `json:"changes,omitempty" gorm:"type:jsonb"` // Значения изменённых полей до и после
+	CreatedAt time.Time    `json:"created_at" gorm:"autoCreateTime,default:now()"`
+}
+
+// Действия, которые попадают в историю задачи
+const (
+	HistoryCreate  = "create"
+	HistoryUpdate  = "update"
+	HistoryDelete  = "delete"
+	HistoryRestore = "restore"
+)
+
+// FieldChange — значение поля до и после изменения в JSON-представлении задачи
+type FieldChange struct {
+	Old json.RawMessage `json:"old"`
+	New json.RawMessage `json:"new"`
+}
+
+// FieldChanges — изменения по именам полей. Хранится в колонке jsonb.
+type FieldChanges map[string]FieldChange
+
+// Value сохраняет изменения в формате JSON
+func (c FieldChanges) Value() (driver.Value, error) {
+	if c == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan читает изменения из колонки jsonb
+func (c *FieldChanges) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*c = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("неподдерживаемый тип изменений: %T", value)
+	}
+	return json.Unmarshal(data, c)
+}
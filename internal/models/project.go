@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// Project — список задач, которым пользуются несколько участников
+type Project struct {
+	ID        int             `json:"id" gorm:"primaryKey"`
+	Name      string          `json:"name" validate:"required,min=3,max=255"`
+	OwnerID   int             `json:"owner_id" gorm:"index"`
+	Members   []ProjectMember `json:"members,omitempty" gorm:"constraint:OnDelete:CASCADE" validate:"-"`
+	CreatedAt time.Time       `json:"created_at" gorm:"autoCreateTime,default:now()"`
+	UpdatedAt time.Time       `json:"updated_at" gorm:"autoUpdateTime,default:now()"`
+}
+
+// ProjectMember — участник проекта и его роль
+type ProjectMember struct {
+	ProjectID int       `json:"project_id" gorm:"primaryKey"`
+	UserID    int       `json:"user_id" gorm:"primaryKey;index" validate:"required"`
+	Role      string    `json:"role" validate:"required,oneof=viewer editor owner"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime,default:now()"`
+}
+
+// MemberRole возвращает роль пользователя в проекте или пустую строку.
+// Участники должны быть загружены.
+func (p Project) MemberRole(userID int) string {
+	for _, m := range p.Members {
+		if m.UserID == userID {
+			return m.Role
+		}
+	}
+	return ""
+}
+
+// OwnerCount возвращает число владельцев проекта
+func (p Project) OwnerCount() int {
+	count := 0
+	for _, m := range p.Members {
+		if m.Role == ProjectRoleOwner {
+			count++
+		}
+	}
+	return count
+}
+
+// Роли участников проекта
+const (
+	ProjectRoleViewer = "viewer" // Только просмотр задач
+	ProjectRoleEditor = "editor" // Создание и изменение задач
+	ProjectRoleOwner  = "owner"  // Управление проектом и участниками
+)
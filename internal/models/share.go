@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// TaskShare — доступ другого пользователя к конкретной задаче
+type TaskShare struct {
+	ID         int       `json:"id" gorm:"primaryKey"`
+	TaskID     int       `json:"task_id" gorm:"uniqueIndex:idx_task_shares_task_user"`
+	Task       *Task     `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	UserID     int       `json:"user_id" gorm:"uniqueIndex:idx_task_shares_task_user;index" validate:"required"`
+	Permission string    `json:"permission" validate:"required,oneof=read edit"`
+	GrantedBy  int       `json:"granted_by"`
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime,default:now()"`
+}
+
+// Права, которые выдаются при совместном доступе к задаче
+const (
+	SharePermissionRead = "read"
+	SharePermissionEdit = "edit"
+)
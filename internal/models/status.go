@@ -0,0 +1,34 @@
+package models
+
+// Статусы задачи. Поле Done выводится из статуса и равно true только для
+// StatusDone.
+const (
+	StatusTodo       = "todo"
+	StatusInProgress = "in_progress"
+	StatusBlocked    = "blocked"
+	StatusReview     = "review"
+	StatusDone       = "done"
+)
+
+// TaskStatuses — все статусы задачи в порядке рабочего процесса
+var TaskStatuses = []string{StatusTodo, StatusInProgress, StatusBlocked, StatusReview, StatusDone}
+
+// DefaultStatusTransitions — разрешённые переходы между статусами, если
+// таблица не задана в конфигурации. Оставить статус прежним можно всегда.
+var DefaultStatusTransitions = map[string][]string{
+	StatusTodo:       {StatusInProgress, StatusBlocked, StatusDone},
+	StatusInProgress: {StatusTodo, StatusBlocked, StatusReview, StatusDone},
+	StatusBlocked:    {StatusTodo, StatusInProgress},
+	StatusReview:     {StatusInProgress, StatusDone},
+	StatusDone:       {StatusTodo, StatusInProgress},
+}
+
+// ValidTaskStatus сообщает, что статус входит в TaskStatuses
+func ValidTaskStatus(status string) bool {
+	for _, s := range TaskStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
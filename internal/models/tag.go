@@ -0,0 +1,23 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Tag — метка задачи. Метки у каждого пользователя свои.
+type Tag struct {
+	ID        int       `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"uniqueIndex:idx_tags_user_name" validate:"required,min=1,max=50"`
+	UserID    int       `json:"user_id" gorm:"uniqueIndex:idx_tags_user_name"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime,default:now()"`
+}
+
+// В задаче метка передаётся просто именем: "tags": ["work", "urgent"]
+func (t Tag) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.Name)
+}
+
+func (t *Tag) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &t.Name)
+}
@@ -1,13 +1,102 @@
 package models
 
-import "time"
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
 
 // Task — структура для задачи
 type Task struct {
-	ID        int       `json:"id" gorm:"primaryKey"`
-	Title     string    `json:"title" validate:"required,min=3,max=255"`
-	Done      bool      `json:"done" gorm:"default:false" validate:"boolean"`
-	UserID    int       `json:"user_id" gorm:"index"`
-	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime,default:now()"`
-	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime,default:now()"`
+	ID           int            `json:"id" gorm:"primaryKey"`
+	Title        string         `json:"title" validate:"required,min=3,max=255"`
+	Done         bool           `json:"done" gorm:"default:false" validate:"boolean"` // Выводится из статуса, оставлено для совместимости
+	Status       string         `json:"status" gorm:"not null;default:todo;index"`    // Статус рабочего процесса, см. TaskStatuses
+	DueDate      *DueDate       `json:"due_date,omitempty" gorm:"index"`
+	Tags         []Tag          `json:"tags" gorm:"many2many:task_tags;constraint:OnDelete:CASCADE" validate:"max=20,dive"`
+	ParentID     *int           `json:"parent_id,omitempty" gorm:"index"`   // Родительская задача, если это подзадача
+	AutoComplete bool           `json:"auto_complete" gorm:"default:false"` // Закрыть задачу, когда закрыты все подзадачи
+	Subtasks     []Task         `json:"subtasks,omitempty" gorm:"-"`        // Заполняется только при выдаче дерева
+	ProjectID    *int           `json:"project_id,omitempty" gorm:"index"`  // Проект, к которому относится задача
+	RRule        string         `json:"rrule,omitempty" gorm:"column:rrule" validate:"omitempty,max=255,rrule"`
+	NextTaskID   *int           `json:"next_task_id,omitempty"` // Следующее повторение, уже созданное при выполнении задачи
+	UserID       int            `json:"user_id" gorm:"index"`
+	Assignees    []TaskAssignee `json:"assignees" gorm:"constraint:OnDelete:CASCADE" validate:"max=20,dive"`
+	Version      int            `json:"version" gorm:"not null;default:1"`        // Растёт при каждом изменении, из неё строится ETag
+	Position     float64        `json:"position" gorm:"not null;default:0;index"` // Место в ручном порядке задач владельца
+	CreatedAt    time.Time      `json:"created_at" gorm:"autoCreateTime,default:now()"`
+	UpdatedAt    time.Time      `json:"updated_at" gorm:"autoUpdateTime,default:now()"`
+	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`                            // Время перемещения в корзину
+	Rank         float64        `json:"rank,omitempty" gorm:"->;-:migration"`      // Релевантность при поиске
+	Highlight    string         `json:"highlight,omitempty" gorm:"->;-:migration"` // Заголовок с подсветкой совпадений
+}
+
+// DueDate — срок выполнения задачи. Принимает как дату (2006-01-02),
+// так и полную метку времени в формате RFC 3339
+type DueDate struct {
+	time.Time
+}
+
+// ParseDueDate разбирает дату или метку времени. Дата без времени
+// трактуется как полночь по UTC.
+func ParseDueDate(s string) (DueDate, error) {
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return DueDate{t}, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return DueDate{}, fmt.Errorf("неверный формат даты %q: ожидается YYYY-MM-DD или RFC 3339", s)
+	}
+	return DueDate{t}, nil
+}
+
+// DateOnly сообщает, что срок задан датой без времени
+func (d DueDate) DateOnly() bool {
+	u := d.UTC()
+	return u.Hour() == 0 && u.Minute() == 0 && u.Second() == 0 && u.Nanosecond() == 0
+}
+
+func (d DueDate) MarshalJSON() ([]byte, error) {
+	if d.DateOnly() {
+		return json.Marshal(d.UTC().Format(time.DateOnly))
+	}
+	return json.Marshal(d.Format(time.RFC3339))
+}
+
+func (d *DueDate) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := ParseDueDate(s)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+func (d DueDate) Value() (driver.Value, error) {
+	return d.Time, nil
+}
+
+func (d *DueDate) Scan(value interface{}) error {
+	if value == nil {
+		d.Time = time.Time{}
+		return nil
+	}
+	t, ok := value.(time.Time)
+	if !ok {
+		return fmt.Errorf("неверный тип срока задачи: %T", value)
+	}
+	d.Time = t
+	return nil
+}
+
+// GormDataType хранит срок как метку времени с часовым поясом
+func (DueDate) GormDataType() string {
+	return "timestamptz"
 }
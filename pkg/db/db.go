@@ -25,7 +25,7 @@ func InitDB() error {
 	if err != nil {
 		return fmt.Errorf("ошибка подключения к базе данных: %v", err)
 	}
-	if err := db.AutoMigrate(&models.User{}, &models.Task{}); err != nil {
+	if err := migrate(db); err != nil {
 		return fmt.Errorf("ошибка миграции: %v", err)
 	}
 	DB = db
@@ -45,10 +45,64 @@ func InitTestDB() string {
 	db.Exec(fmt.Sprintf("CREATE SCHEMA %s", schemaName))
 	db.Exec(fmt.Sprintf("SET search_path TO %s", schemaName))
 
-	if err := db.AutoMigrate(&models.User{}, &models.Task{}); err != nil {
+	if err := migrate(db); err != nil {
 		panic("Ошибка миграции базы: " + err.Error())
 	}
 
 	DB = db
 	return schemaName
 }
+
+// SearchConfig — конфигурация полнотекстового поиска Postgres. simple не
+// зависит от языка, поэтому одинаково работает с русскими и английскими задачами.
+const SearchConfig = "simple"
+
+// migrate создаёт и обновляет схему базы данных
+func migrate(db *gorm.DB) error {
+	hadPosition := db.Migrator().HasColumn(&models.Task{}, "position")
+	hadStatus := db.Migrator().HasColumn(&models.Task{}, "status")
+	if err := db.AutoMigrate(
+		&models.User{},
+		&models.Task{},
+		&models.Tag{},
+		&models.Project{},
+		&models.ProjectMember{},
+		&models.TaskShare{},
+		&models.TaskAssignee{},
+		&models.Comment{},
+		&models.Attachment{},
+		&models.TaskHistory{},
+		&models.CalendarToken{},
+	); err != nil {
+		return err
+	}
+
+	// Ранее журнал удалялся каскадом вместе с задачей
+	if err := db.Exec("ALTER TABLE task_histories DROP CONSTRAINT IF EXISTS fk_task_histories_task").Error; err != nil {
+		return err
+	}
+
+	// Существующие задачи сохраняют прежний порядок по id внутри своего
+	// проекта или среди личных задач владельца
+	if !hadPosition {
+		if err := db.Exec(`UPDATE tasks SET position = ranked.n * 1024
+			FROM (SELECT id, row_number() OVER (PARTITION BY project_id, CASE WHEN project_id IS NULL THEN user_id END ORDER BY id) AS n FROM tasks) AS ranked
+			WHERE tasks.id = ranked.id`).Error; err != nil {
+			return err
+		}
+	}
+	// Завершённые задачи получают статус done, остальные — todo по умолчанию
+	if !hadStatus {
+		if err := db.Exec("UPDATE tasks SET status = ? WHERE done", models.StatusDone).Error; err != nil {
+			return err
+		}
+	}
+
+	// Поисковый вектор вычисляет сама база, GIN-индекс ускоряет запросы @@.
+	// Новые текстовые поля задачи нужно добавить в выражение столбца.
+	if err := db.Exec(fmt.Sprintf(`ALTER TABLE tasks ADD COLUMN IF NOT EXISTS search_vector tsvector
+		GENERATED ALWAYS AS (to_tsvector('%s', coalesce(title, ''))) STORED`, SearchConfig)).Error; err != nil {
+		return err
+	}
+	return db.Exec("CREATE INDEX IF NOT EXISTS idx_tasks_search_vector ON tasks USING GIN (search_vector)").Error
+}
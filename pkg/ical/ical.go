@@ -0,0 +1,139 @@
+// Package ical формирует календари iCalendar (RFC 5545) с задачами VTODO.
+// Строки завершаются CRLF и переносятся после 75 октетов, как требует
+// стандарт.
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Максимальная длина строки содержимого без учёта CRLF
+const maxLineOctets = 75
+
+// Форматы дат iCalendar
+const (
+	dateFormat     = "20060102"
+	dateTimeFormat = "20060102T150405Z"
+)
+
+// Todo — задача календаря
+type Todo struct {
+	UID          string
+	Summary      string
+	Completed    bool
+	Created      time.Time
+	LastModified time.Time
+	Start        *time.Time // Начало, от которого отсчитываются повторения
+	Due          *time.Time
+	DueDateOnly  bool   // Срок и начало без времени выводятся с VALUE=DATE
+	RRule        string // Выводится только вместе с началом: без DTSTART серия не определена
+	Categories   []string
+}
+
+// Encoder последовательно пишет календарь: Begin, задачи Todo, End
+type Encoder struct {
+	w   *bufio.Writer
+	now time.Time
+}
+
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: bufio.NewWriter(w), now: time.Now()}
+}
+
+// Begin открывает календарь. name показывается приложениями как его название.
+func (e *Encoder) Begin(prodID, name string) error {
+	e.line("BEGIN:VCALENDAR")
+	e.line("VERSION:2.0")
+	e.line("PRODID:" + prodID)
+	e.line("CALSCALE:GREGORIAN")
+	if name != "" {
+		e.line("X-WR-CALNAME:" + escapeText(name))
+	}
+	return e.w.Flush()
+}
+
+// Todo добавляет в календарь компонент VTODO
+func (e *Encoder) Todo(t Todo) error {
+	e.line("BEGIN:VTODO")
+	e.line("UID:" + escapeText(t.UID))
+	e.line("DTSTAMP:" + formatDateTime(e.now))
+	e.line("SUMMARY:" + escapeText(t.Summary))
+	if !t.Created.IsZero() {
+		e.line("CREATED:" + formatDateTime(t.Created))
+	}
+	if !t.LastModified.IsZero() {
+		e.line("LAST-MODIFIED:" + formatDateTime(t.LastModified))
+	}
+	if t.Start != nil {
+		e.dateLine("DTSTART", *t.Start, t.DueDateOnly)
+	}
+	if t.Due != nil {
+		e.dateLine("DUE", *t.Due, t.DueDateOnly)
+	}
+	if t.Completed {
+		e.line("STATUS:COMPLETED")
+		e.line("PERCENT-COMPLETE:100")
+	} else {
+		e.line("STATUS:NEEDS-ACTION")
+	}
+	if t.RRule != "" && t.Start != nil {
+		e.line("RRULE:" + t.RRule)
+	}
+	if len(t.Categories) > 0 {
+		categories := make([]string, len(t.Categories))
+		for i, c := range t.Categories {
+			categories[i] = escapeText(c)
+		}
+		e.line("CATEGORIES:" + strings.Join(categories, ","))
+	}
+	e.line("END:VTODO")
+	return e.w.Flush()
+}
+
+// End закрывает календарь
+func (e *Encoder) End() error {
+	e.line("END:VCALENDAR")
+	return e.w.Flush()
+}
+
+// dateLine пишет свойство с датой или, если dateOnly, с датой без времени
+func (e *Encoder) dateLine(name string, t time.Time, dateOnly bool) {
+	if dateOnly {
+		e.line(name + ";VALUE=DATE:" + t.UTC().Format(dateFormat))
+	} else {
+		e.line(name + ":" + formatDateTime(t))
+	}
+}
+
+// line пишет строку содержимого, перенося её по границам символов UTF-8.
+// Продолжение начинается с пробела, который не входит в значение.
+func (e *Encoder) line(s string) {
+	limit := maxLineOctets
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		e.w.WriteString(s[:cut])
+		e.w.WriteString("\r\n ")
+		s = s[cut:]
+		limit = maxLineOctets - 1
+	}
+	e.w.WriteString(s)
+	e.w.WriteString("\r\n")
+}
+
+func formatDateTime(t time.Time) string {
+	return t.UTC().Format(dateTimeFormat)
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// escapeText экранирует значение типа TEXT
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
@@ -0,0 +1,93 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncoder(t *testing.T) {
+	var b strings.Builder
+	e := NewEncoder(&b)
+	e.now = time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	due := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+	e.Begin("-//todo-api//RU", "Задачи")
+	e.Todo(Todo{
+		UID:          "task-1@todo-api",
+		Summary:      "Купить молоко, хлеб; сыр\nи масло",
+		Completed:    true,
+		Created:      time.Date(2029, 12, 31, 10, 0, 0, 0, time.FixedZone("MSK", 3*3600)),
+		LastModified: time.Date(2030, 1, 1, 9, 30, 0, 0, time.UTC),
+		Start:        &due,
+		Due:          &due,
+		DueDateOnly:  true,
+		RRule:        "FREQ=WEEKLY;BYDAY=MO",
+		Categories:   []string{"home", "a,b"},
+	})
+	e.End()
+
+	want := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//todo-api//RU",
+		"CALSCALE:GREGORIAN",
+		"X-WR-CALNAME:Задачи",
+		"BEGIN:VTODO",
+		"UID:task-1@todo-api",
+		"DTSTAMP:20300101T120000Z",
+		`SUMMARY:Купить молоко\, хлеб\; сыр\nи масло`,
+		"CREATED:20291231T070000Z",
+		"LAST-MODIFIED:20300101T093000Z",
+		"DTSTART;VALUE=DATE:20300102",
+		"DUE;VALUE=DATE:20300102",
+		"STATUS:COMPLETED",
+		"PERCENT-COMPLETE:100",
+		"RRULE:FREQ=WEEKLY;BYDAY=MO",
+		`CATEGORIES:home,a\,b`,
+		"END:VTODO",
+		"END:VCALENDAR",
+		"",
+	}, "\r\n")
+	if got := b.String(); got != want {
+		t.Errorf("Неверный календарь:\n%q\nожидался\n%q", got, want)
+	}
+}
+
+func TestEncoder_RRuleWithoutStart(t *testing.T) {
+	var b strings.Builder
+	e := NewEncoder(&b)
+	e.Todo(Todo{UID: "task-2@todo-api", Summary: "Полить цветы", RRule: "FREQ=DAILY"})
+
+	if strings.Contains(b.String(), "RRULE:") {
+		t.Errorf("Правило без DTSTART не должно выводиться:\n%s", b.String())
+	}
+}
+
+func TestLineFolding(t *testing.T) {
+	var b strings.Builder
+	e := NewEncoder(&b)
+	e.line("SUMMARY:" + strings.Repeat("я", 100))
+	e.w.Flush()
+
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\r\n"), "\r\n")
+	if len(lines) < 2 {
+		t.Fatalf("Длинная строка не перенесена: %q", b.String())
+	}
+	var unfolded strings.Builder
+	for i, line := range lines {
+		if len(line) > maxLineOctets {
+			t.Errorf("Строка %d длиннее %d октетов: %d", i, maxLineOctets, len(line))
+		}
+		if i > 0 {
+			if !strings.HasPrefix(line, " ") {
+				t.Errorf("Продолжение %d должно начинаться с пробела", i)
+			}
+			line = line[1:]
+		}
+		unfolded.WriteString(line)
+	}
+	if unfolded.String() != "SUMMARY:"+strings.Repeat("я", 100) {
+		t.Errorf("После склейки строка изменилась: %q", unfolded.String())
+	}
+}
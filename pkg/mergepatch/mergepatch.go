@@ -0,0 +1,38 @@
+package mergepatch
+
+import "encoding/json"
+
+// Apply применяет JSON Merge Patch (RFC 7396) к документу target
+// и возвращает результирующий документ
+func Apply(target, patch []byte) ([]byte, error) {
+	var t, p interface{}
+	if len(target) > 0 {
+		if err := json.Unmarshal(target, &t); err != nil {
+			return nil, err
+		}
+	}
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return nil, err
+	}
+	return json.Marshal(merge(t, p))
+}
+
+func merge(target, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		// Не-объект полностью заменяет цель
+		return patch
+	}
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = map[string]interface{}{}
+	}
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+			continue
+		}
+		targetObj[key] = merge(targetObj[key], value)
+	}
+	return targetObj
+}
@@ -0,0 +1,412 @@
+// Package rrule реализует подмножество правил повторения iCalendar
+// (RFC 5545, RRULE): FREQ=DAILY|WEEKLY|MONTHLY|YEARLY, INTERVAL, COUNT,
+// UNTIL, BYDAY (в том числе 1MO и -1FR), BYMONTHDAY и BYMONTH.
+// Неделя начинается с понедельника.
+package rrule
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Частота повторения
+const (
+	Daily   = "DAILY"
+	Weekly  = "WEEKLY"
+	Monthly = "MONTHLY"
+	Yearly  = "YEARLY"
+)
+
+// Сколько периодов просматривать в поисках вхождения. Защищает от правил
+// без вхождений, например BYMONTH=2;BYMONTHDAY=30.
+const maxPeriods = 10000
+
+// WeekdayNum — день недели из BYDAY с необязательным порядковым номером
+type WeekdayNum struct {
+	N       int // 0 — каждый такой день, 1 — первый, -1 — последний
+	Weekday time.Weekday
+}
+
+// Rule — разобранное правило повторения
+type Rule struct {
+	Freq       string
+	Interval   int
+	Count      int // 0 — без ограничения
+	Until      *time.Time
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []int
+}
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// Parse разбирает строку вида FREQ=WEEKLY;BYDAY=MO. Префикс RRULE: допускается.
+func Parse(s string) (Rule, error) {
+	r := Rule{Interval: 1}
+	s = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(s)), "RRULE:")
+	if s == "" {
+		return r, fmt.Errorf("пустое правило повторения")
+	}
+
+	seen := map[string]bool{}
+	for _, part := range strings.Split(s, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		name = strings.TrimSpace(name)
+		value = strings.TrimSpace(value)
+		if !ok || value == "" {
+			return r, fmt.Errorf("некорректная часть правила %q", part)
+		}
+		if seen[name] {
+			return r, fmt.Errorf("повторяется часть правила %s", name)
+		}
+		seen[name] = true
+
+		var err error
+		switch name {
+		case "FREQ":
+			switch value {
+			case Daily, Weekly, Monthly, Yearly:
+				r.Freq = value
+			default:
+				return r, fmt.Errorf("частота %s не поддерживается", value)
+			}
+		case "INTERVAL":
+			if r.Interval, err = strconv.Atoi(value); err != nil || r.Interval < 1 {
+				return r, fmt.Errorf("некорректный INTERVAL %q", value)
+			}
+		case "COUNT":
+			if r.Count, err = strconv.Atoi(value); err != nil || r.Count < 1 {
+				return r, fmt.Errorf("некорректный COUNT %q", value)
+			}
+		case "UNTIL":
+			until, err := parseUntil(value)
+			if err != nil {
+				return r, err
+			}
+			r.Until = &until
+		case "BYDAY":
+			for _, day := range strings.Split(value, ",") {
+				wd, err := parseWeekdayNum(day)
+				if err != nil {
+					return r, err
+				}
+				r.ByDay = append(r.ByDay, wd)
+			}
+		case "BYMONTHDAY":
+			if r.ByMonthDay, err = parseInts(value, -31, 31); err != nil {
+				return r, fmt.Errorf("некорректный BYMONTHDAY %q", value)
+			}
+		case "BYMONTH":
+			if r.ByMonth, err = parseInts(value, 1, 12); err != nil {
+				return r, fmt.Errorf("некорректный BYMONTH %q", value)
+			}
+		case "WKST":
+			if value != "MO" {
+				return r, fmt.Errorf("поддерживается только WKST=MO")
+			}
+		default:
+			return r, fmt.Errorf("часть правила %s не поддерживается", name)
+		}
+	}
+
+	if r.Freq == "" {
+		return r, fmt.Errorf("не указана частота FREQ")
+	}
+	if r.Count > 0 && r.Until != nil {
+		return r, fmt.Errorf("COUNT и UNTIL нельзя указывать вместе")
+	}
+	for _, wd := range r.ByDay {
+		if wd.N != 0 && r.Freq != Monthly && r.Freq != Yearly {
+			return r, fmt.Errorf("номер дня в BYDAY допустим только для MONTHLY и YEARLY")
+		}
+	}
+	if len(r.ByMonthDay) > 0 && r.Freq == Weekly {
+		return r, fmt.Errorf("BYMONTHDAY нельзя использовать с FREQ=WEEKLY")
+	}
+	return r, nil
+}
+
+func parseUntil(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			if layout == "20060102" {
+				// Дата без времени включает весь день
+				t = t.Add(24*time.Hour - time.Nanosecond)
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("некорректный UNTIL %q", value)
+}
+
+func parseWeekdayNum(s string) (WeekdayNum, error) {
+	if len(s) < 2 {
+		return WeekdayNum{}, fmt.Errorf("некорректный BYDAY %q", s)
+	}
+	wd, ok := weekdays[s[len(s)-2:]]
+	if !ok {
+		return WeekdayNum{}, fmt.Errorf("некорректный BYDAY %q", s)
+	}
+	n := 0
+	if prefix := s[:len(s)-2]; prefix != "" {
+		var err error
+		if n, err = strconv.Atoi(prefix); err != nil || n == 0 || n < -53 || n > 53 {
+			return WeekdayNum{}, fmt.Errorf("некорректный BYDAY %q", s)
+		}
+	}
+	return WeekdayNum{N: n, Weekday: wd}, nil
+}
+
+func parseInts(value string, min, max int) ([]int, error) {
+	var result []int
+	for _, part := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(part)
+		if err != nil || n == 0 || n < min || n > max {
+			return nil, fmt.Errorf("некорректное значение %q", part)
+		}
+		result = append(result, n)
+	}
+	return result, nil
+}
+
+// String возвращает правило в каноническом виде
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, wd := range r.ByDay {
+			days[i] = strings.ToUpper(wd.Weekday.String()[:2])
+			if wd.N != 0 {
+				days[i] = strconv.Itoa(wd.N) + days[i]
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+joinInts(r.ByMonthDay))
+	}
+	if len(r.ByMonth) > 0 {
+		parts = append(parts, "BYMONTH="+joinInts(r.ByMonth))
+	}
+	return strings.Join(parts, ";")
+}
+
+func joinInts(values []int) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.Itoa(v)
+	}
+	return strings.Join(parts, ",")
+}
+
+// Next возвращает до n вхождений серии, начатой в dtstart, строго после after.
+// Само dtstart считается первым вхождением серии и учитывается в COUNT.
+func (r Rule) Next(dtstart, after time.Time, n int) []time.Time {
+	var result []time.Time
+	count := 0
+	for period := 0; period < maxPeriods && len(result) < n; period++ {
+		candidates := r.expand(dtstart, period)
+		if len(candidates) == 0 {
+			continue
+		}
+		for _, t := range candidates {
+			if t.Before(dtstart) {
+				continue
+			}
+			if r.Until != nil && t.After(*r.Until) {
+				return result
+			}
+			count++
+			if r.Count > 0 && count > r.Count {
+				return result
+			}
+			if t.After(after) {
+				result = append(result, t)
+				if len(result) == n {
+					return result
+				}
+			}
+		}
+	}
+	return result
+}
+
+// After возвращает первое вхождение строго после after
+func (r Rule) After(dtstart, after time.Time) (time.Time, bool) {
+	next := r.Next(dtstart, after, 1)
+	if len(next) == 0 {
+		return time.Time{}, false
+	}
+	return next[0], true
+}
+
+// expand возвращает упорядоченные вхождения в period-м периоде от dtstart
+func (r Rule) expand(dtstart time.Time, period int) []time.Time {
+	step := period * r.Interval
+	y, m, d := dtstart.Date()
+	loc := dtstart.Location()
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, dtstart.Hour(), dtstart.Minute(), dtstart.Second(), dtstart.Nanosecond(), loc)
+	}
+
+	var candidates []time.Time
+	switch r.Freq {
+	case Daily:
+		t := at(y, m, d+step)
+		if r.matchMonth(t) && r.matchMonthDay(t) && r.matchWeekday(t) {
+			candidates = append(candidates, t)
+		}
+	case Weekly:
+		// Понедельник недели, в которую попадает dtstart
+		offset := (int(dtstart.Weekday()) + 6) % 7
+		monday := at(y, m, d-offset+7*step)
+		days := r.ByDay
+		if len(days) == 0 {
+			days = []WeekdayNum{{Weekday: dtstart.Weekday()}}
+		}
+		for _, wd := range days {
+			t := monday.AddDate(0, 0, (int(wd.Weekday)+6)%7)
+			if r.matchMonth(t) {
+				candidates = append(candidates, t)
+			}
+		}
+	case Monthly:
+		first := at(y, m+time.Month(step), 1)
+		if r.matchMonth(first) {
+			candidates = r.expandMonth(first, d)
+		}
+	case Yearly:
+		months := r.ByMonth
+		if len(months) == 0 {
+			if len(r.ByDay) > 0 && len(r.ByMonthDay) == 0 {
+				months = []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}
+			} else {
+				months = []int{int(m)}
+			}
+		}
+		for _, month := range months {
+			candidates = append(candidates, r.expandMonth(at(y+step, time.Month(month), 1), d)...)
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Before(candidates[j]) })
+	return dedupe(candidates)
+}
+
+// expandMonth возвращает вхождения внутри месяца, first — его первое число
+func (r Rule) expandMonth(first time.Time, defaultDay int) []time.Time {
+	daysIn := first.AddDate(0, 1, -1).Day()
+	var candidates []time.Time
+	add := func(day int) {
+		if day >= 1 && day <= daysIn {
+			candidates = append(candidates, first.AddDate(0, 0, day-1))
+		}
+	}
+
+	switch {
+	case len(r.ByDay) > 0:
+		for _, wd := range r.ByDay {
+			for _, day := range weekdayDays(first, daysIn, wd) {
+				t := first.AddDate(0, 0, day-1)
+				if r.matchMonthDay(t) {
+					candidates = append(candidates, t)
+				}
+			}
+		}
+	case len(r.ByMonthDay) > 0:
+		for _, day := range r.ByMonthDay {
+			if day < 0 {
+				day = daysIn + day + 1
+			}
+			add(day)
+		}
+	default:
+		// Месяцы без такого числа (31 февраля) пропускаются, как в RFC 5545
+		add(defaultDay)
+	}
+	return candidates
+}
+
+// weekdayDays возвращает числа месяца, которые подходят под элемент BYDAY
+func weekdayDays(first time.Time, daysIn int, wd WeekdayNum) []int {
+	var days []int
+	for day := 1 + (int(wd.Weekday)-int(first.Weekday())+7)%7; day <= daysIn; day += 7 {
+		days = append(days, day)
+	}
+	switch {
+	case wd.N > 0 && wd.N <= len(days):
+		return days[wd.N-1 : wd.N]
+	case wd.N < 0 && -wd.N <= len(days):
+		return days[len(days)+wd.N : len(days)+wd.N+1]
+	case wd.N != 0:
+		return nil
+	}
+	return days
+}
+
+func (r Rule) matchMonth(t time.Time) bool {
+	if len(r.ByMonth) == 0 {
+		return true
+	}
+	for _, month := range r.ByMonth {
+		if time.Month(month) == t.Month() {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Rule) matchMonthDay(t time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	daysIn := time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day()
+	for _, day := range r.ByMonthDay {
+		if day == t.Day() || (day < 0 && daysIn+day+1 == t.Day()) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Rule) matchWeekday(t time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, wd := range r.ByDay {
+		if wd.Weekday == t.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+func dedupe(times []time.Time) []time.Time {
+	result := times[:0]
+	for i, t := range times {
+		if i == 0 || !t.Equal(times[i-1]) {
+			result = append(result, t)
+		}
+	}
+	return result
+}
@@ -0,0 +1,87 @@
+package rrule
+
+import (
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		rule    string
+		dtstart string
+		after   string
+		want    []string
+	}{
+		{"FREQ=DAILY", "2025-01-30 09:00", "2025-01-30 09:00", []string{"2025-01-31 09:00", "2025-02-01 09:00", "2025-02-02 09:00"}},
+		{"FREQ=DAILY;INTERVAL=2;COUNT=3", "2025-01-01 09:00", "2024-12-31 00:00", []string{"2025-01-01 09:00", "2025-01-03 09:00", "2025-01-05 09:00"}},
+		// 6 января 2025 — понедельник
+		{"FREQ=WEEKLY;BYDAY=MO", "2025-01-06 10:00", "2025-01-06 10:00", []string{"2025-01-13 10:00", "2025-01-20 10:00", "2025-01-27 10:00"}},
+		{"FREQ=WEEKLY;BYDAY=MO,WE,FR", "2025-01-08 10:00", "2025-01-08 10:00", []string{"2025-01-10 10:00", "2025-01-13 10:00", "2025-01-15 10:00"}},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=TU", "2025-01-07 10:00", "2025-01-07 10:00", []string{"2025-01-21 10:00", "2025-02-04 10:00", "2025-02-18 10:00"}},
+		// 31-го числа бывает не каждый месяц
+		{"FREQ=MONTHLY", "2025-01-31 12:00", "2025-01-31 12:00", []string{"2025-03-31 12:00", "2025-05-31 12:00", "2025-07-31 12:00"}},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1", "2025-01-31 12:00", "2025-01-31 12:00", []string{"2025-02-28 12:00", "2025-03-31 12:00", "2025-04-30 12:00"}},
+		{"FREQ=MONTHLY;BYDAY=-1FR", "2025-01-01 00:00", "2025-01-01 00:00", []string{"2025-01-31 00:00", "2025-02-28 00:00", "2025-03-28 00:00"}},
+		{"FREQ=MONTHLY;BYDAY=1MO", "2025-01-01 00:00", "2025-01-01 00:00", []string{"2025-01-06 00:00", "2025-02-03 00:00", "2025-03-03 00:00"}},
+		{"FREQ=YEARLY", "2024-02-29 00:00", "2024-02-29 00:00", []string{"2028-02-29 00:00", "2032-02-29 00:00", "2036-02-29 00:00"}},
+		{"FREQ=YEARLY;BYMONTH=3,9;BYMONTHDAY=1", "2025-01-01 00:00", "2025-01-01 00:00", []string{"2025-03-01 00:00", "2025-09-01 00:00", "2026-03-01 00:00"}},
+		{"FREQ=DAILY;UNTIL=20250103", "2025-01-01 09:00", "2025-01-01 09:00", []string{"2025-01-02 09:00", "2025-01-03 09:00"}},
+		{"FREQ=DAILY;COUNT=2", "2025-01-01 09:00", "2025-01-01 09:00", []string{"2025-01-02 09:00"}},
+		// Правило без вхождений не зацикливается
+		{"FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30", "2025-01-01 00:00", "2025-01-01 00:00", nil},
+	}
+
+	for _, tt := range tests {
+		rule, err := Parse(tt.rule)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.rule, err)
+		}
+		got := rule.Next(date(tt.dtstart), date(tt.after), 3)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: ожидалось %v, получено %v", tt.rule, tt.want, got)
+			continue
+		}
+		for i := range got {
+			if !got[i].Equal(date(tt.want[i])) {
+				t.Errorf("%s: вхождение %d: ожидалось %s, получено %s", tt.rule, i, tt.want[i], got[i].Format("2006-01-02 15:04"))
+			}
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"BYDAY=MO",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;COUNT=2;UNTIL=20250101",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=DAILY;BYSETPOS=1",
+		"FREQ=DAILY;FREQ=WEEKLY",
+	} {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("Parse(%q): ожидалась ошибка", s)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	rule, err := Parse("rrule:freq=monthly;byday=-1fr;count=5")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if got, want := rule.String(), "FREQ=MONTHLY;COUNT=5;BYDAY=-1FR"; got != want {
+		t.Errorf("String() = %q, ожидалось %q", got, want)
+	}
+}
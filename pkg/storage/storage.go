@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ErrNotFound возвращается, если объекта с таким ключом нет
+var ErrNotFound = errors.New("объект не найден")
+
+// Storage — хранилище содержимого файлов
+type Storage interface {
+	// Put сохраняет size байт из r под ключом key
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get открывает объект на чтение, вызывающий обязан закрыть его
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete удаляет объект. Отсутствие объекта ошибкой не считается.
+	Delete(ctx context.Context, key string) error
+}
+
+// Store — хранилище, выбранное при запуске
+var Store Storage
+
+// InitStorage выбирает хранилище по переменной STORAGE_DRIVER: local (по
+// умолчанию) или s3 для S3-совместимых сервисов, например MinIO
+func InitStorage() error {
+	switch driver := os.Getenv("STORAGE_DRIVER"); driver {
+	case "", "local":
+		dir := os.Getenv("STORAGE_LOCAL_PATH")
+		if dir == "" {
+			dir = "uploads"
+		}
+		local, err := NewLocal(dir)
+		if err != nil {
+			return err
+		}
+		Store = local
+	case "s3":
+		Store = NewS3(S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+		})
+	default:
+		return fmt.Errorf("неизвестное хранилище %q", driver)
+	}
+	return nil
+}
+
+// validKey отсекает пустые ключи и попытки выйти за пределы хранилища
+func validKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") {
+		return fmt.Errorf("некорректный ключ %q", key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return fmt.Errorf("некорректный ключ %q", key)
+		}
+	}
+	return nil
+}